@@ -17,32 +17,138 @@ package backoff
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
-func init() {
-	rand.Seed(time.Now().UTC().UnixNano())
-}
+// jitter is random but our own so we do not need to seed the global source
+var jitter = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// kinds of policy
+const (
+	// Stepped waits about as long as the step table says
+	Stepped = "steps"
+	// Exponential doubles the longest wait each attempt up to Cap
+	// the wait is chosen at random up to that (full jitter)
+	Exponential = "exponential"
+	// Decorrelated waits between Base and three times the last wait up to Cap
+	Decorrelated = "decorrelated"
+	// Fixed always waits Base
+	Fixed = "fixed"
+)
+
+// waits are never longer than this if a policy has no cap
+const defaultCap = 40 * time.Second
 
 // Policy for retrying
 type Policy struct {
+	// how waits grow. Stepped if empty.
+	Kind string
+	// table used by Stepped in ms
 	Steps []int
+	// first wait
+	Base time.Duration
+	// longest wait
+	Cap time.Duration
 }
 
 // DefaultPolicy backoff policy in ms
 var DefaultPolicy = Policy{
-	[]int{0, 10, 10, 100, 100, 500, 500, 3000, 3000, 5000, 5000, 10000, 10000, 20000, 20000, 40000, 40000},
+	Steps: []int{0, 10, 10, 100, 100, 500, 500, 3000, 3000, 5000, 5000, 10000, 10000, 20000, 20000, 40000, 40000},
+}
+
+// New starts counting attempts
+func (p *Policy) New() *Backoff {
+	return &Backoff{p: p}
+}
+
+// Backoff gives the waits for one run of attempts
+type Backoff struct {
+	p    *Policy
+	n    int
+	prev time.Duration
 }
 
-// Duration gives how long we should wait on the given attempt
-func (p *Policy) Duration(n int) time.Duration {
-	if n >= len(p.Steps) {
-		n = len(p.Steps) - 1
+// Reset starts over as if nothing had failed
+func (b *Backoff) Reset() {
+	b.n = 0
+	b.prev = 0
+}
+
+// Next gives how long we should wait before the next attempt
+func (b *Backoff) Next() (d time.Duration) {
+	p := b.p
+	limit := p.Cap
+	if limit <= 0 {
+		limit = defaultCap
+	}
+
+	switch p.Kind {
+	case Exponential:
+		ceil := p.Base
+		for i := 0; i < b.n && ceil < limit; i++ {
+			ceil *= 2
+		}
+		d = between(0, shorter(ceil, limit))
+	case Decorrelated:
+		prev := longer(b.prev, p.Base)
+		d = shorter(between(p.Base, 3*prev), limit)
+	case Fixed:
+		d = p.Base
+	default:
+		d = b.step()
 	}
-	duration := p.Steps[n]
+
+	b.n++
+	b.prev = d
+
+	return
+}
+
+func (b *Backoff) step() time.Duration {
+	steps := b.p.Steps
+	if len(steps) == 0 {
+		steps = DefaultPolicy.Steps
+	}
+	n := b.n
+	if n >= len(steps) {
+		n = len(steps) - 1
+	}
+	duration := steps[n]
 	if duration > 0 {
 		// random int from uniform distribution in range of duration
-		duration = duration/2 + rand.Intn(duration)
+		duration = duration/2 + int(randInt63n(int64(duration)))
 	}
 	return time.Duration(duration) * time.Millisecond
 }
+
+// a random duration in [lo, hi]
+func between(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(randInt63n(int64(hi-lo)+1))
+}
+
+func randInt63n(n int64) int64 {
+	jitter.Lock()
+	defer jitter.Unlock()
+	return jitter.Int63n(n)
+}
+
+func shorter(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func longer(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+func TestNext(t *testing.T) {
+	cases := []struct {
+		p Policy
+		// the longest wait allowed on each attempt
+		most []time.Duration
+		// the shortest wait allowed on each attempt
+		least []time.Duration
+	}{
+		{
+			Policy{Steps: []int{0, 100, 1000}},
+			[]time.Duration{0, 150 * time.Millisecond, 1500 * time.Millisecond, 1500 * time.Millisecond},
+			[]time.Duration{0, 50 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			Policy{Kind: Exponential, Base: time.Second, Cap: 5 * time.Second},
+			[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+			[]time.Duration{0, 0, 0, 0, 0},
+		},
+		{
+			Policy{Kind: Decorrelated, Base: time.Second, Cap: 10 * time.Second},
+			[]time.Duration{3 * time.Second, 9 * time.Second, 10 * time.Second, 10 * time.Second},
+			[]time.Duration{time.Second, time.Second, time.Second, time.Second},
+		},
+		{
+			Policy{Kind: Fixed, Base: time.Second},
+			[]time.Duration{time.Second, time.Second},
+			[]time.Duration{time.Second, time.Second},
+		},
+	}
+
+	for _, c := range cases {
+		// jitter is random so try a few times
+		for i := 0; i < 100; i++ {
+			b := c.p.New()
+			for n := range c.most {
+				d := b.Next()
+				if d > c.most[n] || d < c.least[n] {
+					t.Fatalf("%s attempt %d: %s not in [%s, %s]", c.p.Kind, n, d, c.least[n], c.most[n])
+				}
+			}
+		}
+	}
+
+	// a reset starts over
+	b := (&Policy{Steps: []int{0, 1000}}).New()
+	b.Next()
+	b.Reset()
+	if d := b.Next(); d != 0 {
+		t.Errorf("expected the first step after a reset: %s", d)
+	}
+}
+
+func TestFor(t *testing.T) {
+	if p := For("www.example.com", Stream); p != &DefaultPolicy {
+		t.Errorf("expected the default policy: %+v", p)
+	}
+
+	options.Set("backoff.default.kind", Fixed)
+	options.Set("backoff.default.base", "1s")
+	options.Set("backoff.upcoming.kind", Fixed)
+	options.Set("backoff.upcoming.base", "2s")
+	options.Set("backoff.example.kind", Fixed)
+	options.Set("backoff.example.base", "3s")
+	options.Set("backoff.example.stream.kind", Exponential)
+	options.Set("backoff.example.stream.base", "4s")
+	options.Set("backoff.example.stream.cap", "1m")
+	options.Set("backoff.broken.kind", "sometimes")
+
+	cases := []struct {
+		hostname, op string
+		kind         string
+		base         time.Duration
+	}{
+		{"www.example.com", Stream, Exponential, 4 * time.Second},
+		{"example.com", Recover, Fixed, 3 * time.Second},
+		{"www.other.com", Upcoming, Fixed, 2 * time.Second},
+		{"hls", Recover, Fixed, time.Second},
+		// a policy that is wrong is skipped
+		{"broken.com", Recover, Fixed, time.Second},
+	}
+
+	for _, c := range cases {
+		p := For(c.hostname, c.op)
+		if p.Kind != c.kind || p.Base != c.base {
+			t.Errorf("For(%q, %q) = %+v; want %s %s", c.hostname, c.op, p, c.kind, c.base)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package backoff
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+// operations that may have their own policy
+const (
+	// Stream is looking for a stream that should be starting
+	Stream = "stream"
+	// Upcoming is checking when targets will be live
+	Upcoming = "upcoming"
+	// Recover is finding a stream or connection again after it dropped
+	Recover = "recover"
+)
+
+// For gives the policy set in options for an operation of the module with the given hostname
+// the first policy found is used:
+//
+//	[backoff.SITE.OP]
+//	[backoff.SITE]
+//	[backoff.OP]
+//	[backoff.default]
+//
+// SITE is the hostname without 'www.' up to the first dot such as 'showroom-live'
+// DefaultPolicy is used when none are set
+func For(hostname, op string) *Policy {
+	s := site(hostname)
+	for _, name := range []string{s + "." + op, s, op, "default"} {
+		p, err := fromOptions("backoff." + name)
+		if err != nil {
+			log.Println("backoff.For:", err)
+			continue
+		}
+		if p != nil {
+			return p
+		}
+	}
+
+	return &DefaultPolicy
+}
+
+// the part of a hostname used in option names
+func site(hostname string) string {
+	hostname = strings.TrimPrefix(hostname, "www.")
+	if i := strings.Index(hostname, "."); i >= 0 {
+		hostname = hostname[:i]
+	}
+	return hostname
+}
+
+// reads a policy from options
+// nil if it is not set
+func fromOptions(key string) (*Policy, error) {
+	if !options.IsSet(key + ".kind") {
+		return nil, nil
+	}
+
+	p := &Policy{
+		Kind:  options.Get(key + ".kind"),
+		Steps: options.GetIntSlice(key + ".steps"),
+		Base:  options.GetDuration(key + ".base"),
+		Cap:   options.GetDuration(key + ".cap"),
+	}
+
+	switch p.Kind {
+	case Stepped:
+		if len(p.Steps) == 0 {
+			return nil, fmt.Errorf("%s: 'steps' needs a list of waits in ms", key)
+		}
+	case Exponential, Decorrelated, Fixed:
+		if p.Base <= 0 {
+			return nil, fmt.Errorf("%s: 'base' must be greater than 0", key)
+		}
+		if p.Cap > 0 && p.Cap < p.Base {
+			return nil, fmt.Errorf("%s: 'cap' must be at least 'base'", key)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown kind %q", key, p.Kind)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,77 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/rpc"
+	"os"
+
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/track"
+	"github.com/spf13/cobra"
+)
+
+var icsPath string
+
+func init() {
+	rootCmd.AddCommand(calendarCmd)
+	calendarCmd.Flags().StringVar(&icsPath, "ics", "-", "Write the calendar to this file ('-' for stdout)")
+}
+
+var calendarCmd = &cobra.Command{
+	Use:   "calendar",
+	Short: "Exports upcoming streams as an iCalendar file",
+	Long: `Exports the streams autosr is waiting for as an iCalendar (.ics) file.
+autosr must be running. Calendar apps can also subscribe to http://localhost:4846/calendar.ics
+where the port is the one set by 'listen_on'.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		remote, err := rpc.DialHTTP("tcp", options.Get("listen_on"))
+		if err != nil {
+			fmt.Println("We cannot connect to the server. Is autosr running?")
+			return
+		}
+		defer remote.Close()
+
+		var scheduled []track.Scheduled
+		if err := remote.Call("Command.Schedule", "", &scheduled); err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+
+		var w io.Writer = os.Stdout
+		if icsPath != "-" {
+			f, err := os.Create(icsPath)
+			if err != nil {
+				fmt.Println("error:", err)
+				return
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if err := track.WriteCalendar(w, scheduled); err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+		if icsPath != "-" {
+			fmt.Printf("[ok] wrote %d upcoming streams to %s\n", len(scheduled), icsPath)
+		}
+	},
+}
@@ -0,0 +1,193 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bobbytrapz/autosr/showroom"
+	"github.com/bobbytrapz/autosr/track"
+	"github.com/spf13/cobra"
+)
+
+var shouldAddAll bool
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.AddCommand(importShowroomFollowsCmd)
+	importShowroomFollowsCmd.Flags().BoolVarP(&shouldAddAll, "yes", "y", false, "Add every room without asking")
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Adds targets to the track list from somewhere else",
+}
+
+var importShowroomFollowsCmd = &cobra.Command{
+	Use:   "showroom-follows",
+	Short: "Adds the rooms you follow on SHOWROOM",
+	Long: `Adds the rooms followed by your SHOWROOM account to the track list.
+You need to be logged in. See showroom_cookies and showroom-login.json in the README.
+Rooms you already track are left out and you choose which of the rest to add.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		followed, err := showroom.FetchFollows(ctx)
+		if err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+
+		listed, err := track.ListedLinks(ctx)
+		if err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+		isListed := make(map[string]bool)
+		for _, link := range listed {
+			isListed[link] = true
+		}
+
+		var rooms []showroom.FollowedRoom
+		for _, r := range followed {
+			if !isListed[r.Link()] {
+				rooms = append(rooms, r)
+			}
+		}
+
+		fmt.Printf("You follow %d rooms and already track %d of them.\n", len(followed), len(followed)-len(rooms))
+		if len(rooms) == 0 {
+			fmt.Println("Nothing to add.")
+			return
+		}
+
+		fmt.Println()
+		for ndx, r := range rooms {
+			fmt.Printf("%3d) %s\n     %s\n", ndx+1, r.Name, r.Link())
+		}
+		fmt.Println()
+
+		chosen := make([]int, len(rooms))
+		for ndx := range chosen {
+			chosen[ndx] = ndx
+		}
+		if !shouldAddAll {
+			fmt.Print("Add which rooms? [all, none or numbers like 1,3-5]: ")
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			chosen, err = parseSelection(answer, len(rooms))
+			if err != nil {
+				fmt.Println("error:", err)
+				return
+			}
+		}
+		if len(chosen) == 0 {
+			fmt.Println("Nothing added.")
+			return
+		}
+
+		// make sure each room can be tracked before we list it
+		var links []string
+		for _, ndx := range chosen {
+			r := rooms[ndx]
+			if err := validateTarget(ctx, r.Link()); err != nil {
+				fmt.Printf("skip %s: %s\n", r.Name, err)
+				continue
+			}
+			links = append(links, r.Link())
+		}
+
+		comment := fmt.Sprintf("followed on SHOWROOM (imported %s)", time.Now().Format("2006-01-02"))
+		if err := track.AppendList(comment, links...); err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+		fmt.Printf("[ok] added %d rooms to the track list\n", len(links))
+	},
+}
+
+// asks the module for a link if it can track it
+func validateTarget(ctx context.Context, link string) error {
+	m, err := track.FindModuleForLink(link)
+	if err != nil {
+		return err
+	}
+	t, err := m.AddTarget(ctx, link)
+	if err != nil {
+		return err
+	}
+	if t == nil || t.Name() == "" {
+		return fmt.Errorf("could not find the room")
+	}
+
+	return nil
+}
+
+// parseSelection reads an answer such as "all", "none" or "1,3-5"
+// gives zero-based indexes
+func parseSelection(answer string, n int) (chosen []int, err error) {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	switch answer {
+	case "all", "a", "y", "yes":
+		for ndx := 0; ndx < n; ndx++ {
+			chosen = append(chosen, ndx)
+		}
+		return
+	case "", "none", "n", "no":
+		return
+	}
+
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(answer, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to := part, part
+		if sp := strings.SplitN(part, "-", 2); len(sp) == 2 {
+			from, to = strings.TrimSpace(sp[0]), strings.TrimSpace(sp[1])
+		}
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("not a number: %q", from)
+		}
+		last, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, fmt.Errorf("not a number: %q", to)
+		}
+		if first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("choose from 1 to %d: %q", n, part)
+		}
+
+		for num := first; num <= last; num++ {
+			if !seen[num-1] {
+				seen[num-1] = true
+				chosen = append(chosen, num-1)
+			}
+		}
+	}
+
+	return
+}
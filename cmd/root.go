@@ -28,8 +28,11 @@ import (
 	"time"
 
 	"github.com/bobbytrapz/autosr/dashboard"
+	// use hls module
+	_ "github.com/bobbytrapz/autosr/hls"
 	"github.com/bobbytrapz/autosr/ipc"
 	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/plugin"
 	// use showroom module
 	_ "github.com/bobbytrapz/autosr/showroom"
 	"github.com/bobbytrapz/autosr/track"
@@ -108,6 +111,7 @@ Dashboard controls:
 	q - Quit dashboard. Use 'autosr stop' to actually stop autosr
 	r or c - Check if any streams are on right away
 	click or Enter - Open link to stream in web browser
+	s - Show stats for the selected target
 	Home - Jump to top of list
 
 This program comes with ABSOLUTELY NO WARRANTY;
@@ -149,6 +153,11 @@ Details can be found at https://www.gnu.org/licenses/gpl-3.0.en.html.
 		// start ipc
 		ipc.Start(ctx)
 
+		// module programs must be known before tracking starts
+		if err := plugin.Load(ctx); err != nil {
+			fmt.Println(err)
+		}
+
 		// start tracking
 		if err := track.Start(ctx); err != nil {
 			panic(err)
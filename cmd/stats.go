@@ -0,0 +1,71 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bobbytrapz/autosr/stats"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [target]",
+	Short: "Shows statistics about recorded streams",
+	Long: `Shows how often and how long each target streams based on what autosr has recorded.
+Give a link or part of a name to see when a target usually streams and how reliable their announcements are.
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sessions, err := stats.Load()
+		if err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+
+		summaries := stats.Summarize(sessions)
+		if len(summaries) == 0 {
+			fmt.Println("Nothing has been recorded yet.")
+			return
+		}
+
+		if len(args) == 0 {
+			if err := stats.Output(os.Stdout, summaries); err != nil {
+				fmt.Println("error:", err)
+			}
+			return
+		}
+
+		found := stats.Find(summaries, args[0])
+		switch len(found) {
+		case 0:
+			fmt.Printf("Nothing has been recorded for %q.\n", args[0])
+		case 1:
+			if err := found[0].OutputDetail(os.Stdout); err != nil {
+				fmt.Println("error:", err)
+			}
+		default:
+			if err := stats.Output(os.Stdout, found); err != nil {
+				fmt.Println("error:", err)
+			}
+		}
+	},
+}
@@ -0,0 +1,68 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/bobbytrapz/autosr/ipc"
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows what autosr is doing",
+	Long: `Shows how many targets are live, upcoming and offline and how each site is responding.
+A site that keeps failing is not asked again for a while. autosr must be running.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		remote, err := rpc.DialHTTP("tcp", options.Get("listen_on"))
+		if err != nil {
+			fmt.Println("We cannot connect to the server. Is autosr running?")
+			return
+		}
+		defer remote.Close()
+
+		var res ipc.Dashboard
+		if err := remote.Call("Command.Status", ipc.Dashboard{SelectURL: "?"}, &res); err != nil {
+			fmt.Println("error:", err)
+			return
+		}
+
+		t := res.TrackTable
+		fmt.Printf("%d live, %d upcoming, %d offline\n", len(t.Live), len(t.Upcoming), len(t.Offline))
+		for _, row := range t.Live {
+			if row.TicketRequired {
+				fmt.Println(row.Name, "is live but a ticket is required")
+			}
+		}
+
+		if len(res.Hosts) == 0 {
+			fmt.Println("No requests have been made yet.")
+			return
+		}
+		for _, h := range res.Hosts {
+			fmt.Println(h)
+		}
+	},
+}
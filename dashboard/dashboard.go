@@ -23,7 +23,9 @@ import (
 	"time"
 
 	"github.com/bobbytrapz/autosr/ipc"
+	"github.com/bobbytrapz/autosr/limit"
 	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/stats"
 	"github.com/bobbytrapz/autosr/track"
 	"github.com/jroimartin/gocui"
 )
@@ -35,6 +37,16 @@ var req = ipc.Dashboard{
 }
 var res ipc.Dashboard
 
+// rows in the order they are drawn in the target list
+// the index of a row is its line number in the view
+var lines []track.DisplayRow
+
+// id of the row under the cursor so it stays selected when the list reorders
+var selectedID int
+
+// stats for the selected target are shown over the target list
+var statsText string
+
 var shouldColorLogo = false
 
 var logoHeight = 2
@@ -114,7 +126,12 @@ func redraw(g *gocui.Gui) {
 		if v := g.CurrentView(); v != nil {
 			switch v.Name() {
 			case "target-list":
+				v.Title = failingHosts()
 				drawTargetList(v)
+				if ndx := lineOf(selectedID); ndx >= 0 {
+					// keep the same target selected
+					return placeCursor(v, ndx)
+				}
 				// fix cursor
 				_, cy := v.Cursor()
 				if l, err := v.Line(cy); err == nil && strings.TrimSpace(l) == "" {
@@ -141,6 +158,7 @@ func drawTargetList(v *gocui.View) {
 	v.Clear()
 	v.SelBgColor = 0
 	v.SelFgColor = 0
+	lines = res.TrackTable.Lines()
 
 	if numRows() == 0 {
 		fmt.Fprintln(v, "Written by Bobby. (@pibisubukebe)")
@@ -156,6 +174,17 @@ func drawTargetList(v *gocui.View) {
 	res.TrackTable.Output(v)
 }
 
+// warns about sites we have stopped asking for a while
+func failingHosts() string {
+	var failing []string
+	for _, h := range res.Hosts {
+		if h.State != limit.Closed {
+			failing = append(failing, h.String())
+		}
+	}
+	return strings.Join(failing, ", ")
+}
+
 func layout(g *gocui.Gui) error {
 	w, h := g.Size()
 	if v, err := g.SetView("logo", -1, -1, w, logoHeight); err != nil {
@@ -176,6 +205,18 @@ func layout(g *gocui.Gui) error {
 		drawTargetList(v)
 	}
 
+	if statsText != "" {
+		lw := w / 8
+		if v, err := g.SetView("stats", lw, logoHeight+1, w-lw, h-2); err != nil {
+			if err != gocui.ErrUnknownView {
+				return err
+			}
+
+			v.Title = "stats (s to close)"
+			fmt.Fprint(v, statsText)
+		}
+	}
+
 	if _, err := g.SetCurrentView("target-list"); err != nil {
 		return err
 	}
@@ -232,6 +273,14 @@ func keys(g *gocui.Gui) (err error) {
 		return
 	}
 
+	if err = g.SetKeybinding("target-list", 's', gocui.ModNone, toggleStats); err != nil {
+		return
+	}
+
+	if err = g.SetKeybinding("target-list", gocui.KeyEsc, gocui.ModNone, closeStats); err != nil {
+		return
+	}
+
 	// mouse
 	if err = g.SetKeybinding("target-list", gocui.MouseLeft, gocui.ModNone, openTarget); err != nil {
 		return
@@ -252,6 +301,7 @@ func call(method string) error {
 	res.TrackTable.Live = nil
 	res.TrackTable.Upcoming = nil
 	res.TrackTable.Offline = nil
+	res.Hosts = nil
 
 	if err := remote.Call("Command."+method, req, &res); err != nil {
 		return fmt.Errorf("dashboard.call: %s", err)
@@ -268,91 +318,66 @@ func numRows() int {
 	return len(res.TrackTable.Live) + len(res.TrackTable.Upcoming) + len(res.TrackTable.Offline)
 }
 
-func moveUp(g *gocui.Gui, v *gocui.View) error {
-	if v == nil {
-		return nil
+// gives the line number of the row with the given id or -1
+func lineOf(id int) int {
+	if id == 0 {
+		return -1
 	}
+	for ndx, row := range lines {
+		if row.ID == id {
+			return ndx
+		}
+	}
+	return -1
+}
 
+// moves the cursor to a line scrolling only if it is out of view
+func placeCursor(v *gocui.View, ndx int) error {
+	_, h := v.Size()
 	ox, oy := v.Origin()
-	cx, cy := v.Cursor()
-	if err := v.SetCursor(cx, cy-1); err != nil && oy > 0 {
-		if err := v.SetOrigin(ox, oy-1); err != nil {
-			debug(fmt.Sprintf("origin: %d %d", ox, oy-1))
-			return err
-		}
+	cx, _ := v.Cursor()
+	if ndx < oy {
+		oy = ndx
+	} else if h > 0 && ndx >= oy+h {
+		oy = ndx - h + 1
 	}
-	if l, err := v.Line(cy - 1); err == nil && strings.TrimSpace(l) == "" {
-		return moveUp(g, v)
+	if err := v.SetOrigin(ox, oy); err != nil {
+		return err
 	}
-
-	return nil
+	return v.SetCursor(cx, ndx-oy)
 }
 
-func moveDown(g *gocui.Gui, v *gocui.View) error {
+// selects the nearest target in the given direction skipping separators
+func moveBy(v *gocui.View, dy int) error {
 	if v == nil {
 		return nil
 	}
 
-	numSeparators := 0
-	if len(res.TrackTable.Live) > 0 {
-		numSeparators++
-	}
-	if len(res.TrackTable.Upcoming) > 0 {
-		numSeparators++
-	}
-	ox, oy := v.Origin()
-	cx, cy := v.Cursor()
-	if oy+cy-numSeparators+1 > numRows() {
-		return nil
-	}
-
-	if err := v.SetCursor(cx, cy+1); err != nil {
-		if err := v.SetOrigin(ox, oy+1); err != nil {
-			debug(fmt.Sprintf("origin: %d %d", ox, oy+1))
-			return err
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	for ndx := oy + cy + dy; ndx >= 0 && ndx < len(lines); ndx += dy {
+		if lines[ndx].ID != 0 {
+			selectedID = lines[ndx].ID
+			return placeCursor(v, ndx)
 		}
 	}
-	if l, err := v.Line(cy + 1); err == nil && strings.TrimSpace(l) == "" {
-		return moveDown(g, v)
-	}
 
 	return nil
 }
 
-func selected(v *gocui.View) (row track.DisplayRow) {
-	_, cy := v.Cursor()
-	line, err := v.Line(cy)
-	if err != nil {
-		return
-	}
-
-	sp := strings.SplitN(line, " ", 3)
-	var name string
-	if len(sp) == 2 {
-		name = sp[1]
-	} else if len(sp) == 3 {
-		name = sp[2]
-	} else {
-		return
-	}
-	name = strings.TrimLeft(name, " ")
-
-	for _, row := range res.TrackTable.Live {
-		if row.Name == name {
-			return row
-		}
-	}
+func moveUp(g *gocui.Gui, v *gocui.View) error {
+	return moveBy(v, -1)
+}
 
-	for _, row := range res.TrackTable.Upcoming {
-		if row.Name == name {
-			return row
-		}
-	}
+func moveDown(g *gocui.Gui, v *gocui.View) error {
+	return moveBy(v, 1)
+}
 
-	for _, row := range res.TrackTable.Offline {
-		if row.Name == name {
-			return row
-		}
+func selected(v *gocui.View) (row track.DisplayRow) {
+	_, oy := v.Origin()
+	_, cy := v.Cursor()
+	if ndx := oy + cy; ndx >= 0 && ndx < len(lines) {
+		row = lines[ndx]
 	}
 
 	return
@@ -360,6 +385,7 @@ func selected(v *gocui.View) (row track.DisplayRow) {
 
 func openTarget(g *gocui.Gui, v *gocui.View) error {
 	row := selected(v)
+	selectedID = row.ID
 	if row.Link == "" {
 		return nil
 	}
@@ -383,5 +409,40 @@ func openHomepage(g *gocui.Gui, v *gocui.View) error {
 func scrollToTop(g *gocui.Gui, v *gocui.View) error {
 	v.SetOrigin(0, 0)
 	v.SetCursor(0, 0)
+	selectedID = selected(v).ID
 	return nil
 }
+
+func toggleStats(g *gocui.Gui, v *gocui.View) error {
+	if statsText != "" {
+		return closeStats(g, v)
+	}
+
+	row := selected(v)
+	if row.Link == "" {
+		return nil
+	}
+
+	var summaries []stats.Summary
+	if err := remote.Call("Command.Stats", row.Link, &summaries); err != nil {
+		return fmt.Errorf("dashboard.toggleStats: %s", err)
+	}
+
+	var b strings.Builder
+	if len(summaries) == 0 {
+		fmt.Fprintf(&b, "%s\n%s\n\nNothing has been recorded yet.\n", row.Name, row.Link)
+	} else if err := summaries[0].OutputDetail(&b); err != nil {
+		return fmt.Errorf("dashboard.toggleStats: %s", err)
+	}
+	statsText = b.String()
+
+	return nil
+}
+
+func closeStats(g *gocui.Gui, v *gocui.View) error {
+	if statsText == "" {
+		return nil
+	}
+	statsText = ""
+	return g.DeleteView("stats")
+}
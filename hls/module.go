@@ -0,0 +1,121 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package hls
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/track"
+)
+
+// Module tracks any HLS playlist
+// a link is either the playlist itself or a page with 'stream_regex' set in the track list
+type Module struct{}
+
+var module = Module{}
+
+func init() {
+	if err := track.RegisterModule(module); err != nil {
+		panic(err)
+	}
+}
+
+// Hostname gives the name for this module
+// playlists can be on any host so links are matched with MatchLink
+func (m Module) Hostname() string {
+	return "hls"
+}
+
+// AnyHost keeps "hls" out of the supported sites
+func (m Module) AnyHost() bool {
+	return true
+}
+
+// MatchLink gives true for playlist links and pages we were told how to search
+func (m Module) MatchLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	return isPlaylist(u) || track.Option(link, "stream_regex") != ""
+}
+
+func isPlaylist(u *url.URL) bool {
+	return strings.HasSuffix(strings.ToLower(u.Path), ".m3u8")
+}
+
+// AddTarget to track
+func (m Module) AddTarget(ctx context.Context, link string) (track.Target, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("hls.AddTarget: '%s' %s", link, err)
+	}
+
+	t := &target{
+		link: link,
+		name: track.Option(link, "name"),
+	}
+	if t.name == "" {
+		t.name = nameFromURL(u)
+	}
+
+	if !isPlaylist(u) {
+		if t.find, err = compileStreamRegex(track.Option(link, "stream_regex")); err != nil {
+			return nil, fmt.Errorf("hls.AddTarget: '%s' %s", link, err)
+		}
+	}
+
+	return t, nil
+}
+
+// CheckUpcoming snipes every target whose playlist is live
+// streams here are not announced ahead of time
+func (m Module) CheckUpcoming(ctx context.Context, targets []track.Target) error {
+	if len(targets) == 0 {
+		return nil
+	}
+	log.Println("hls.CheckUpcoming:", len(targets), "targets")
+
+	var waitCheck sync.WaitGroup
+	for _, t := range targets {
+		waitCheck.Add(1)
+		go func(t track.Target) {
+			defer waitCheck.Done()
+
+			// the snipe must outlive the check
+			checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+
+			if _, err := t.CheckStream(checkCtx); err != nil {
+				return
+			}
+			log.Println("hls.CheckUpcoming:", t.Name(), "is live now!")
+			if err := track.SnipeTargetAt(ctx, t, time.Now()); err != nil {
+				log.Println("hls.CheckUpcoming:", err)
+			}
+		}(t)
+	}
+	waitCheck.Wait()
+
+	return nil
+}
@@ -0,0 +1,235 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package hls
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bobbytrapz/autosr/limit"
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/retry"
+	"github.com/bobbytrapz/autosr/track"
+)
+
+var httpClient = http.Client{
+	Timeout:   30 * time.Second,
+	Transport: &limit.Transport{},
+}
+
+// we only need the start of a playlist or page
+const maxBodySize = 4 << 20
+
+var errNotLive = fmt.Errorf("playlist is %w", track.ErrNotLive)
+
+type target struct {
+	name string
+	link string
+	// finds the playlist in a page
+	// nil when the link is the playlist
+	find *regexp.Regexp
+}
+
+// the first group is used if there is one
+func compileStreamRegex(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, errors.New("stream_regex is required for a page that is not a playlist")
+	}
+
+	return regexp.Compile(expr)
+}
+
+// example.com-live for https://example.com/live/index.m3u8
+func nameFromURL(u *url.URL) string {
+	dir := strings.Trim(path.Dir(u.Path), "/")
+	if dir == "" || dir == "." {
+		dir = strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	}
+	dir = strings.ReplaceAll(dir, "/", "-")
+	if dir == "" || dir == "." || dir == "-" {
+		return u.Hostname()
+	}
+
+	return u.Hostname() + "-" + dir
+}
+
+func get(ctx context.Context, link string) (body []byte, err error) {
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", options.Get("user_agent"))
+	req = req.WithContext(ctx)
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%s: %s", link, res.Status)
+		return
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, maxBodySize))
+}
+
+// gives the playlist url for the target
+func (t *target) playlistURL(ctx context.Context) (string, error) {
+	if t.find == nil {
+		return t.link, nil
+	}
+
+	page, err := get(ctx, t.link)
+	if err != nil {
+		return "", err
+	}
+
+	return findPlaylist(t.link, page, t.find)
+}
+
+func findPlaylist(link string, page []byte, find *regexp.Regexp) (string, error) {
+	m := find.FindSubmatch(page)
+	if m == nil {
+		return "", errNotLive
+	}
+	found := m[0]
+	if len(m) > 1 {
+		found = m[1]
+	}
+
+	// playlists are often found inside json
+	s := strings.ReplaceAll(string(found), `\/`, "/")
+	s = strings.ReplaceAll(s, "&amp;", "&")
+
+	base, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+// a playlist is live if it has not ended
+// a master playlist has no end so it is live while it is served
+func isLivePlaylist(body []byte) bool {
+	s := bufio.NewScanner(strings.NewReader(string(body)))
+	first := true
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		if first {
+			if !strings.HasPrefix(line, "#EXTM3U") {
+				return false
+			}
+			first = false
+			continue
+		}
+		if strings.HasPrefix(line, "#EXT-X-ENDLIST") {
+			return false
+		}
+	}
+
+	return !first
+}
+
+// gives the playlist url if it is live
+func (t *target) checkPlaylist(ctx context.Context) (string, error) {
+	link, err := t.playlistURL(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := get(ctx, link)
+	if err != nil {
+		return "", err
+	}
+	if !isLivePlaylist(body) {
+		return "", errNotLive
+	}
+
+	return link, nil
+}
+
+// Reload callback
+func (t *target) Reload(ctx context.Context) {}
+
+// BeginSnipe callback
+func (t *target) BeginSnipe(ctx context.Context) {}
+
+// BeginSave callback
+func (t *target) BeginSave(ctx context.Context) {}
+
+// EndSave callback
+func (t *target) EndSave(ctx context.Context) {}
+
+// Display for display in dashboard
+func (t *target) Display() string {
+	return t.name
+}
+
+// Name is given in the track list or made from the link
+func (t *target) Name() string {
+	return t.name
+}
+
+// Link is the playlist or the page where it is found
+func (t *target) Link() string {
+	return t.link
+}
+
+// CheckLive gives true if the playlist is live
+func (t *target) CheckLive(ctx context.Context) (isLive bool, err error) {
+	if _, err = t.checkPlaylist(ctx); err == nil {
+		return true, nil
+	}
+
+	err = retry.Temporary(fmt.Errorf("%s is not live yet: %w", t.name, err))
+
+	return
+}
+
+// CheckStream gives the playlist url if it is live
+func (t *target) CheckStream(ctx context.Context) (streamURL string, err error) {
+	if streamURL, err = t.checkPlaylist(ctx); err == nil {
+		return
+	}
+
+	err = retry.Temporary(fmt.Errorf("%s has no stream yet: %s", t.name, err))
+
+	return
+}
+
+// SavePath decides where videos are saved
+func (t *target) SavePath() string {
+	return t.name
+}
@@ -0,0 +1,115 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package hls
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+const livePlaylist = "#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXTINF:2.0,\nseg1.ts\n"
+const endedPlaylist = livePlaylist + "#EXT-X-ENDLIST\n"
+
+func TestIsLivePlaylist(t *testing.T) {
+	cases := map[string]bool{
+		livePlaylist:    true,
+		endedPlaylist:   false,
+		"<html></html>": false,
+		"":              false,
+	}
+
+	for body, want := range cases {
+		if got := isLivePlaylist([]byte(body)); got != want {
+			t.Errorf("isLivePlaylist(%q) = %v; want %v", body, got, want)
+		}
+	}
+}
+
+func TestFindPlaylist(t *testing.T) {
+	find := regexp.MustCompile(`"hls":"([^"]+)"`)
+	page := []byte(`{"hls":"\/live\/index.m3u8?a=1&amp;b=2"}`)
+
+	got, err := findPlaylist("https://example.com/watch/1", page, find)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://example.com/live/index.m3u8?a=1&b=2"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	if _, err := findPlaylist("https://example.com/watch/1", []byte("offline"), find); err != errNotLive {
+		t.Errorf("expected errNotLive: %v", err)
+	}
+}
+
+func TestNameFromURL(t *testing.T) {
+	cases := map[string]string{
+		"https://example.com/live/main/index.m3u8": "example.com-live-main",
+		"https://example.com/stream.m3u8":          "example.com-stream",
+		"https://example.com/":                     "example.com",
+	}
+
+	for link, want := range cases {
+		u, _ := url.Parse(link)
+		if got := nameFromURL(u); got != want {
+			t.Errorf("nameFromURL(%q) = %q; want %q", link, got, want)
+		}
+	}
+}
+
+func TestCheckStream(t *testing.T) {
+	playlist := livePlaylist
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			fmt.Fprint(w, `<video src="/live/index.m3u8">`)
+		case "/live/index.m3u8":
+			fmt.Fprint(w, playlist)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	direct := &target{name: "direct", link: srv.URL + "/live/index.m3u8"}
+	page := &target{name: "page", link: srv.URL + "/page", find: regexp.MustCompile(`src="([^"]+\.m3u8)"`)}
+
+	for _, tt := range []*target{direct, page} {
+		streamURL, err := tt.CheckStream(ctx)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if streamURL != srv.URL+"/live/index.m3u8" {
+			t.Errorf("%s: got %q", tt.name, streamURL)
+		}
+	}
+
+	playlist = endedPlaylist
+	if isLive, err := direct.CheckLive(ctx); isLive || err == nil {
+		t.Error("expected an ended playlist to not be live")
+	}
+
+	missing := &target{name: "missing", link: srv.URL + "/gone.m3u8"}
+	if _, err := missing.CheckStream(ctx); err == nil {
+		t.Error("expected an error for a missing playlist")
+	}
+}
@@ -13,34 +13,32 @@
 // You should have received a copy of the GNU General Public License
 // along with autosr.  If not, see <https://www.gnu.org/licenses/>.
 
-package retry
+package ipc
 
 import (
-	"time"
-)
-
-// TimeRetryable errors can be retried if the caller wants
-type TimeRetryable interface {
-	Retry() (string, error)
-}
+	"log"
+	"net/http"
 
-// TimeCheck will give a Retryable and true if we can try again
-func TimeCheck(err error) (TimeRetryable, bool) {
-	t, ok := err.(TimeRetryable)
-	return t, ok
-}
+	"github.com/bobbytrapz/autosr/track"
+)
 
-// TimeError is an error we can retry
-type TimeError struct {
-	Message string
-	Attempt func() (time.Time, error)
-}
+// Schedule gives the upcoming streams for a link or every target if link is empty
+func (c *Command) Schedule(link string, res *[]track.Scheduled) error {
+	*res = nil
+	for _, s := range track.Schedule() {
+		if link == "" || s.Link == link {
+			*res = append(*res, s)
+		}
+	}
 
-func (e TimeError) Error() string {
-	return e.Message
+	return nil
 }
 
-// Retry makes another attempt
-func (e TimeError) Retry() (time.Time, error) {
-	return e.Attempt()
+// calendar apps can subscribe to this
+func webCalendar(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := track.WriteCalendar(w, track.Schedule()); err != nil {
+		log.Println("ipc.webCalendar:", err)
+	}
 }
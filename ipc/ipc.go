@@ -40,6 +40,7 @@ func Start(ctx context.Context) {
 	c := &Command{}
 	rpc.Register(c)
 	rpc.HandleHTTP()
+	handleWeb(http.DefaultServeMux)
 
 	server = &http.Server{
 		Addr: addr,
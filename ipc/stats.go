@@ -13,30 +13,31 @@
 // You should have received a copy of the GNU General Public License
 // along with autosr.  If not, see <https://www.gnu.org/licenses/>.
 
-package retry
+package ipc
 
-// BoolRetryable errors can be retried if the caller wants
-type BoolRetryable interface {
-	Retry() (bool, error)
-}
+import (
+	"github.com/bobbytrapz/autosr/stats"
+)
 
-// BoolCheck will give a Retryable and true if we can try again
-func BoolCheck(err error) (BoolRetryable, bool) {
-	t, ok := err.(BoolRetryable)
-	return t, ok
-}
+// Stats gives a summary of recorded sessions for a link or every target if link is empty
+func (c *Command) Stats(link string, res *[]stats.Summary) error {
+	sessions, err := stats.Load()
+	if err != nil {
+		return err
+	}
 
-// BoolError is an error we can retry
-type BoolError struct {
-	Message string
-	Attempt func() (bool, error)
-}
+	summaries := stats.Summarize(sessions)
+	if link == "" {
+		*res = summaries
+		return nil
+	}
 
-func (e BoolError) Error() string {
-	return e.Message
-}
+	*res = nil
+	for _, s := range summaries {
+		if s.Link == link {
+			*res = append(*res, s)
+		}
+	}
 
-// Retry makes another attempt
-func (e BoolError) Retry() (bool, error) {
-	return e.Attempt()
+	return nil
 }
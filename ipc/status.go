@@ -18,6 +18,7 @@ package ipc
 import (
 	"fmt"
 
+	"github.com/bobbytrapz/autosr/limit"
 	"github.com/bobbytrapz/autosr/track"
 )
 
@@ -25,6 +26,8 @@ import (
 type Dashboard struct {
 	SelectURL  string
 	TrackTable track.DisplayTable
+	// sites we have been making requests to
+	Hosts []limit.Host
 }
 
 var status Dashboard
@@ -43,6 +46,7 @@ func replicate(req *Dashboard, res *Dashboard) {
 	copy(res.TrackTable.Upcoming, d.Upcoming)
 	res.TrackTable.Offline = make([]track.DisplayRow, len(d.Offline))
 	copy(res.TrackTable.Offline, d.Offline)
+	res.Hosts = limit.Hosts()
 }
 
 // Status for the dashboard
@@ -0,0 +1,299 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package ipc
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bobbytrapz/autosr/limit"
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/stats"
+	"github.com/bobbytrapz/autosr/track"
+)
+
+//go:embed web
+var webAssets embed.FS
+
+// web dashboard is served alongside rpc on the same server
+func handleWeb(mux *http.ServeMux) {
+	assets, err := fs.Sub(webAssets, "web")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("/", guard(http.FileServer(http.FS(assets)).ServeHTTP))
+	mux.HandleFunc("/api/status", guard(webStatus))
+	mux.HandleFunc("/api/events", guard(webEvents))
+	mux.HandleFunc("/api/check", guard(webCheck))
+	mux.HandleFunc("/api/targets", guard(webTargets))
+	mux.HandleFunc("/api/stop", guard(webStop))
+	mux.HandleFunc("/api/files", guard(webFiles))
+	mux.HandleFunc("/api/download", guard(webDownload))
+	mux.HandleFunc("/api/stats", guard(webStats))
+	mux.HandleFunc("/api/hosts", guard(webHosts))
+	mux.HandleFunc("/calendar.ics", guard(webCalendar))
+}
+
+// guard only answers requests meant for this server
+// a name other than localhost may be pointed at us by another site (DNS rebinding)
+// so we only answer to addresses and names the user gave us. changes must come from our own pages.
+func guard(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAddress(r.Host) && !isAllowedHost(r.Host) {
+			log.Println("ipc.guard: refused host:", r.Host)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			// browsers always say where a cross-site request came from
+			if origin := r.Header.Get("Origin"); origin != "" {
+				if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+					log.Println("ipc.guard: refused origin:", origin)
+					http.Error(w, "forbidden", http.StatusForbidden)
+					return
+				}
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+// localhost or an ip address
+func isAddress(hostport string) bool {
+	host := hostName(hostport)
+	return host == "localhost" || net.ParseIP(host) != nil
+}
+
+// the host we listen on or one set in 'web_allowed_hosts'
+func isAllowedHost(hostport string) bool {
+	host := hostName(hostport)
+	if host == "" {
+		return false
+	}
+	allowed := append([]string{options.Get("listen_on")}, options.GetStringSlice("web_allowed_hosts")...)
+	for _, a := range allowed {
+		if strings.EqualFold(hostName(a), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// the host without a port
+func hostName(hostport string) string {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	return strings.Trim(host, "[]")
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("ipc.writeJSON:", err)
+	}
+}
+
+func requirePost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func webStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, track.Display())
+}
+
+func webHosts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, limit.Hosts())
+}
+
+// sends the track table whenever it changes
+func webEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	tick := time.NewTicker(1 * time.Second)
+	defer tick.Stop()
+
+	var last []byte
+	for {
+		data, err := json.Marshal(track.Display())
+		if err != nil {
+			log.Println("ipc.webEvents:", err)
+			return
+		}
+		if !bytes.Equal(data, last) {
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+			last = data
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+func webCheck(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	log.Println("ipc.webCheck")
+	track.CheckNow()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// targets are added and removed through the track list so it stays the source of truth
+func webTargets(w http.ResponseWriter, r *http.Request) {
+	link := strings.TrimSpace(r.FormValue("link"))
+	if link == "" {
+		http.Error(w, "link is required", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		log.Println("ipc.webTargets: add", link)
+		err = track.AppendList("added from the web dashboard", link)
+	case http.MethodDelete:
+		log.Println("ipc.webTargets: remove", link)
+		err = track.UnlistTarget(r.Context(), link)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func webStop(w http.ResponseWriter, r *http.Request) {
+	if !requirePost(w, r) {
+		return
+	}
+	link := r.FormValue("link")
+	log.Println("ipc.webStop:", link)
+	if err := track.StopSave(link); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type savedFile struct {
+	Name      string
+	Size      int64
+	ModTime   time.Time
+	Recording bool
+}
+
+func webFiles(w http.ResponseWriter, r *http.Request) {
+	root := options.Get("save_to")
+
+	recording := make(map[string]bool)
+	for _, p := range track.SavingPaths() {
+		recording[filepath.Clean(p)] = true
+	}
+
+	var files []savedFile
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, savedFile{
+			Name:      filepath.ToSlash(rel),
+			Size:      info.Size(),
+			ModTime:   info.ModTime(),
+			Recording: recording[filepath.Clean(p)],
+		})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// most recent first
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].ModTime.After(files[b].ModTime)
+	})
+
+	writeJSON(w, files)
+}
+
+func webDownload(w http.ResponseWriter, r *http.Request) {
+	root := options.Get("save_to")
+	name := filepath.FromSlash(r.FormValue("name"))
+
+	// only serve files under the save directory
+	p := filepath.Join(root, filepath.Clean(string(filepath.Separator)+name))
+	if rel, err := filepath.Rel(root, p); err != nil || strings.HasPrefix(rel, "..") {
+		http.Error(w, "invalid file", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(p)))
+	http.ServeFile(w, r, p)
+}
+
+func webStats(w http.ResponseWriter, r *http.Request) {
+	var summaries []stats.Summary
+	if err := (&Command{}).Stats(r.FormValue("link"), &summaries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, summaries)
+}
@@ -0,0 +1,208 @@
+'use strict';
+
+const $ = (id) => document.getElementById(id);
+
+function showError(msg) {
+  $('error').textContent = msg || '';
+}
+
+async function send(method, url, params) {
+  const body = params ? new URLSearchParams(params) : undefined;
+  const res = await fetch(url, { method, body });
+  if (!res.ok) {
+    showError(await res.text());
+    return false;
+  }
+  showError('');
+  return true;
+}
+
+function cell(row, text, cls) {
+  const td = row.insertCell();
+  if (cls) {
+    td.className = cls;
+  }
+  if (text instanceof Node) {
+    td.appendChild(text);
+  } else {
+    td.textContent = text;
+  }
+  return td;
+}
+
+function button(label, onclick) {
+  const b = document.createElement('button');
+  b.textContent = label;
+  b.addEventListener('click', onclick);
+  return b;
+}
+
+function link(href, text) {
+  const a = document.createElement('a');
+  a.href = href;
+  a.target = '_blank';
+  a.rel = 'noopener';
+  a.textContent = text;
+  return a;
+}
+
+function drawRows(table, rows, isLive) {
+  table.innerHTML = '';
+  for (const r of rows || []) {
+    const row = table.insertRow();
+    cell(row, r.Status, r.TicketRequired ? 'status ticket' : 'status');
+    const name = cell(row, link(r.Link, r.Name));
+    if (r.Title) {
+      const title = document.createElement('span');
+      title.className = 'title';
+      title.textContent = r.Title;
+      name.appendChild(title);
+    }
+    const actions = cell(row, '', 'actions');
+    if (isLive && !r.TicketRequired) {
+      actions.appendChild(button('Stop', () => {
+        if (confirm('Stop recording ' + r.Name + '?')) {
+          send('POST', '/api/stop', { link: r.Link });
+        }
+      }));
+    }
+    actions.appendChild(button('Remove', () => {
+      if (confirm('Stop tracking ' + r.Name + '?')) {
+        send('DELETE', '/api/targets?' + new URLSearchParams({ link: r.Link }));
+      }
+    }));
+  }
+}
+
+function drawTable(t) {
+  drawRows($('live'), t.Live, true);
+  drawRows($('upcoming'), t.Upcoming, false);
+  drawRows($('offline'), t.Offline, false);
+}
+
+function formatSize(n) {
+  const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+  return n.toFixed(i ? 1 : 0) + ' ' + units[i];
+}
+
+async function drawFiles() {
+  const res = await fetch('/api/files');
+  if (!res.ok) {
+    showError(await res.text());
+    return;
+  }
+  const files = await res.json();
+  const table = $('files');
+  table.innerHTML = '';
+  for (const f of files || []) {
+    const row = table.insertRow();
+    cell(row, new Date(f.ModTime).toLocaleString(), 'status');
+    if (f.Recording) {
+      cell(row, f.Name + ' (recording)', 'recording');
+    } else {
+      cell(row, link('/api/download?' + new URLSearchParams({ name: f.Name }), f.Name));
+    }
+    cell(row, formatSize(f.Size), 'actions');
+  }
+}
+
+function formatDuration(ns) {
+  const m = Math.floor(ns / 6e10);
+  return Math.floor(m / 60) + 'h' + String(m % 60).padStart(2, '0') + 'm';
+}
+
+const weekdays = ['Sun', 'Mon', 'Tue', 'Wed', 'Thu', 'Fri', 'Sat'];
+
+function usualHours(s) {
+  const parts = [];
+  s.TypicalHour.forEach((hour, day) => {
+    if (hour >= 0) {
+      parts.push(weekdays[day] + ' ' + String(hour).padStart(2, '0') + ':00');
+    }
+  });
+  return parts.join(', ');
+}
+
+async function drawStats() {
+  const res = await fetch('/api/stats');
+  if (!res.ok) {
+    showError(await res.text());
+    return;
+  }
+  const summaries = await res.json();
+  const table = $('stats');
+  table.innerHTML = '';
+  const head = table.createTHead().insertRow();
+  for (const h of ['Name', 'Streams', 'Total', 'Average', 'On time', 'Usual start']) {
+    cell(head, h);
+  }
+  const body = table.createTBody();
+  for (const s of summaries || []) {
+    const row = body.insertRow();
+    cell(row, link(s.Link, s.Name));
+    cell(row, s.Count);
+    cell(row, formatDuration(s.Total));
+    cell(row, formatDuration(s.Average));
+    cell(row, s.Announced ? s.OnTime + '/' + s.Announced : '-');
+    cell(row, usualHours(s));
+  }
+}
+
+// warns about sites we have stopped asking for a while
+async function drawHosts() {
+  const res = await fetch('/api/hosts');
+  if (!res.ok) {
+    return;
+  }
+  const hosts = await res.json();
+  const failing = [];
+  for (const h of hosts || []) {
+    if (h.State === 'open') {
+      const secs = Math.max(0, Math.round((new Date(h.RetryAt) - Date.now()) / 1000));
+      failing.push(h.Name + ' is failing. asking again in ' + secs + 's');
+    } else if (h.State === 'half-open') {
+      failing.push(h.Name + ' is failing. checking if it has recovered');
+    }
+  }
+  $('hosts').textContent = failing.join(', ');
+}
+
+function connect() {
+  const events = new EventSource('/api/events');
+  events.onopen = () => {
+    $('connection').textContent = 'connected';
+    $('connection').className = 'online';
+  };
+  events.onmessage = (ev) => {
+    drawTable(JSON.parse(ev.data));
+  };
+  events.onerror = () => {
+    $('connection').textContent = 'disconnected';
+    $('connection').className = 'offline';
+  };
+}
+
+$('check').addEventListener('click', () => send('POST', '/api/check'));
+
+$('refresh-files').addEventListener('click', drawFiles);
+
+$('refresh-stats').addEventListener('click', drawStats);
+
+$('add').addEventListener('submit', async (ev) => {
+  ev.preventDefault();
+  const form = ev.target;
+  if (await send('POST', '/api/targets', { link: form.link.value })) {
+    form.reset();
+  }
+});
+
+connect();
+drawFiles();
+drawStats();
+drawHosts();
+setInterval(drawHosts, 5000);
@@ -0,0 +1,73 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package ipc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+func TestGuard(t *testing.T) {
+	listenOn := options.Get("listen_on")
+	allowed := options.GetStringSlice("web_allowed_hosts")
+	defer func() {
+		options.Set("listen_on", listenOn)
+		options.Set("web_allowed_hosts", allowed)
+	}()
+	options.Set("listen_on", "mybox:4846")
+	options.Set("web_allowed_hosts", []string{"autosr.home.lan"})
+
+	h := guard(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cases := []struct {
+		method, host, origin string
+		want                 int
+	}{
+		{"GET", "localhost:4846", "", http.StatusNoContent},
+		{"GET", "192.168.1.10:4846", "", http.StatusNoContent},
+		{"GET", "[::1]:4846", "", http.StatusNoContent},
+		// another site's name pointed at us
+		{"GET", "evil.example.com:4846", "", http.StatusForbidden},
+		// names the user gave us
+		{"GET", "MyBox:4846", "", http.StatusNoContent},
+		{"GET", "autosr.home.lan:4846", "", http.StatusNoContent},
+		{"POST", "autosr.home.lan:4846", "http://autosr.home.lan:4846", http.StatusNoContent},
+		{"GET", "home.lan:4846", "", http.StatusForbidden},
+		{"POST", "localhost:4846", "http://localhost:4846", http.StatusNoContent},
+		// not from a browser
+		{"POST", "127.0.0.1:4846", "", http.StatusNoContent},
+		{"POST", "localhost:4846", "https://evil.example.com", http.StatusForbidden},
+		{"DELETE", "localhost:4846", "null", http.StatusForbidden},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, "/api/stop", nil)
+		r.Host = c.host
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		if w.Code != c.want {
+			t.Errorf("%s %s from %q: got %d; want %d", c.method, c.host, c.origin, w.Code, c.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package limit keeps us from making too many requests to a site
+//
+// Each host gets a token bucket and a circuit breaker that are shared by
+// everything that talks to it. When a host keeps failing we stop asking it
+// for a while instead of every target trying again on its own.
+package limit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/retry"
+)
+
+// states of a host
+const (
+	// Closed lets requests through
+	Closed = "closed"
+	// Open refuses requests until the host has had time to recover
+	Open = "open"
+	// HalfOpen lets one request through to see if the host has recovered
+	HalfOpen = "half-open"
+)
+
+const (
+	// how many failures in a row before we stop asking a host
+	maxFailures = 5
+	// how long we stop asking at first
+	// doubled each time the host is still failing
+	minCooldown = 30 * time.Second
+	maxCooldown = 5 * time.Minute
+	// how long others wait while we see if a host has recovered
+	trialWait = time.Second
+)
+
+// ErrOpen is given instead of making a request to a host that keeps failing
+var ErrOpen = errors.New("limit: too many failures")
+
+// tests change the time
+var now = time.Now
+
+var mu sync.Mutex
+var hosts = make(map[string]*host)
+
+type host struct {
+	name string
+
+	// token bucket
+	tokens   float64
+	filledAt time.Time
+
+	// circuit breaker
+	state     string
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+	// a request is seeing if the host has recovered
+	trial bool
+}
+
+func lookup(name string) *host {
+	mu.Lock()
+	defer mu.Unlock()
+
+	h, ok := hosts[name]
+	if !ok {
+		h = &host{name: name, state: Closed, filledAt: now(), tokens: float64(burst())}
+		hosts[name] = h
+	}
+
+	return h
+}
+
+func rate() int {
+	return options.GetInt("request_rate")
+}
+
+func burst() int {
+	if b := options.GetInt("request_burst"); b > 1 {
+		return b
+	}
+	return 1
+}
+
+// takes a token and gives how long we must wait for it
+func (h *host) reserve() time.Duration {
+	r := rate()
+	if r <= 0 {
+		return 0
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	t := now()
+	h.tokens += t.Sub(h.filledAt).Seconds() * float64(r)
+	if b := float64(burst()); h.tokens > b {
+		h.tokens = b
+	}
+	h.filledAt = t
+
+	h.tokens--
+	if h.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-h.tokens / float64(r) * float64(time.Second))
+}
+
+// gives an error if we should not make a request right now
+// trial is true if the request is the one seeing if the host has recovered
+func (h *host) allow() (trial bool, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	t := now()
+	switch h.state {
+	case Open:
+		if t.Before(h.openUntil) {
+			return false, retry.After(fmt.Errorf("%s: %w", h.name, ErrOpen), h.openUntil.Sub(t))
+		}
+		log.Println("limit:", h.name, "checking if it has recovered")
+		h.state = HalfOpen
+		h.trial = true
+		return true, nil
+	case HalfOpen:
+		if h.trial {
+			return false, retry.After(fmt.Errorf("%s: %w", h.name, ErrOpen), trialWait)
+		}
+		h.trial = true
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// keeps track of how requests to a host turned out
+// retryAfter is how long the host asked us to wait
+func (h *host) record(trial bool, ok bool, retryAfter time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if trial {
+		h.trial = false
+	} else if h.state == HalfOpen {
+		// a request made before the host failed does not tell us if it has recovered
+		return
+	}
+	if ok {
+		if h.state != Closed {
+			log.Println("limit:", h.name, "recovered")
+		}
+		h.state = Closed
+		h.failures = 0
+		h.cooldown = 0
+		return
+	}
+
+	h.failures++
+	switch {
+	case h.state == HalfOpen:
+		h.cooldown = 2 * h.cooldown
+		if h.cooldown > maxCooldown {
+			h.cooldown = maxCooldown
+		}
+	case h.failures >= maxFailures:
+		h.cooldown = minCooldown
+	default:
+		return
+	}
+
+	wait := h.cooldown
+	if retryAfter > wait {
+		wait = retryAfter
+	}
+	h.state = Open
+	h.openUntil = now().Add(wait)
+	log.Println("limit:", h.name, "keeps failing. waiting until", h.openUntil.Format(time.Kitchen))
+}
+
+// a request was given up before we learned anything about the host
+func (h *host) abandon(trial bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if trial {
+		h.trial = false
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Transport limits the requests made by an http.Client
+type Transport struct {
+	// makes the requests
+	// http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+// RoundTrip waits for its turn then makes the request unless the host keeps failing
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	h := lookup(req.URL.Host)
+	trial, err := h.allow()
+	if err != nil {
+		return nil, err
+	}
+	if err := wait(req.Context(), h.reserve()); err != nil {
+		h.abandon(trial)
+		return nil, err
+	}
+
+	res, err := base.RoundTrip(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		h.abandon(trial)
+	case err != nil:
+		h.record(trial, false, 0)
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		h.record(trial, false, RetryAfter(res.Header.Get("Retry-After"), now()))
+	default:
+		h.record(trial, true, 0)
+	}
+
+	return res, err
+}
+
+// Host is how we are treating requests to a host
+type Host struct {
+	Name     string
+	State    string
+	Failures int
+	// when we ask again if the host is open
+	RetryAt time.Time
+}
+
+// String describes the host for people
+func (h Host) String() string {
+	switch h.State {
+	case Open:
+		return fmt.Sprintf("%s is failing. asking again in %s", h.Name, time.Until(h.RetryAt).Truncate(time.Second))
+	case HalfOpen:
+		return fmt.Sprintf("%s is failing. checking if it has recovered", h.Name)
+	}
+	return fmt.Sprintf("%s is ok", h.Name)
+}
+
+// Hosts gives every host we have made requests to
+func Hosts() []Host {
+	mu.Lock()
+	defer mu.Unlock()
+
+	res := make([]Host, 0, len(hosts))
+	for _, h := range hosts {
+		s := Host{
+			Name:     h.name,
+			State:    h.state,
+			Failures: h.failures,
+		}
+		if h.state == Open {
+			s.RetryAt = h.openUntil
+		}
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// RetryAfter gives how long a Retry-After header asks us to wait
+// it may be a number of seconds or a date
+func RetryAfter(h string, now time.Time) time.Duration {
+	h = strings.TrimSpace(h)
+	if h == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(h); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(h); err == nil {
+		return at.Sub(now)
+	}
+	return 0
+}
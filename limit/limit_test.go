@@ -0,0 +1,229 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package limit
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/retry"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// a host that answers with status
+func fakeHost(t *testing.T, status *int, calls *int) *http.Client {
+	t.Helper()
+	return &http.Client{
+		Transport: &Transport{
+			Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				*calls++
+				return &http.Response{
+					StatusCode: *status,
+					Header:     make(http.Header),
+					Body:       http.NoBody,
+					Request:    req,
+				}, nil
+			}),
+		},
+	}
+}
+
+// stops time until the test moves it
+func fakeNow(t *testing.T) func(time.Duration) {
+	t.Helper()
+	at := time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		return at
+	}
+	t.Cleanup(func() {
+		now = time.Now
+	})
+	return func(d time.Duration) {
+		at = at.Add(d)
+	}
+}
+
+func state(name string) Host {
+	for _, h := range Hosts() {
+		if h.Name == name {
+			return h
+		}
+	}
+	return Host{}
+}
+
+func TestBreaker(t *testing.T) {
+	advance := fakeNow(t)
+	options.Set("request_rate", 0)
+
+	status := http.StatusServiceUnavailable
+	calls := 0
+	client := fakeHost(t, &status, &calls)
+	get := func() error {
+		res, err := client.Get("http://breaker.test/")
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	for i := 0; i < maxFailures; i++ {
+		if err := get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := state("breaker.test"); s.State != Open || s.Failures != maxFailures {
+		t.Fatalf("expected the host to be open: %+v", s)
+	}
+
+	// we do not ask while it is open
+	err := get()
+	if !errors.Is(err, ErrOpen) || retry.Delay(err) != minCooldown || calls != maxFailures {
+		t.Errorf("expected to wait %s without asking: %v %s %d", minCooldown, err, retry.Delay(err), calls)
+	}
+
+	// one request sees if it recovered and it has not
+	advance(minCooldown)
+	if err := get(); err != nil || calls != maxFailures+1 {
+		t.Fatalf("expected a trial request: %v %d", err, calls)
+	}
+	if s := state("breaker.test"); s.State != Open || !s.RetryAt.Equal(now().Add(2*minCooldown)) {
+		t.Errorf("expected to wait twice as long: %+v", s)
+	}
+
+	// now it has
+	advance(2 * minCooldown)
+	status = http.StatusOK
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+	if s := state("breaker.test"); s.State != Closed || s.Failures != 0 {
+		t.Errorf("expected the host to be closed: %+v", s)
+	}
+}
+
+func TestHalfOpen(t *testing.T) {
+	fakeNow(t)
+	options.Set("request_rate", 0)
+
+	h := lookup("half-open.test")
+	h.state = Open
+	h.cooldown = minCooldown
+	h.openUntil = now()
+
+	trial, err := h.allow()
+	if err != nil || !trial {
+		t.Fatalf("expected a trial: %t %v", trial, err)
+	}
+	// only one request checks at a time
+	if _, err := h.allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("expected others to wait: %v", err)
+	}
+	h.abandon(trial)
+	if trial, err = h.allow(); err != nil || !trial {
+		t.Errorf("expected a new trial after one gave up: %t %v", trial, err)
+	}
+
+	// a host asking us to wait longer is listened to
+	h.record(trial, false, time.Hour)
+	if s := state("half-open.test"); !s.RetryAt.Equal(now().Add(time.Hour)) {
+		t.Errorf("expected to wait an hour: %+v", s)
+	}
+}
+
+func TestOldRequestDuringTrial(t *testing.T) {
+	fakeNow(t)
+	options.Set("request_rate", 0)
+
+	h := lookup("old-request.test")
+	old, err := h.allow()
+	if err != nil || old {
+		t.Fatalf("expected an ordinary request: %t %v", old, err)
+	}
+
+	// the host fails while the old request is still waiting
+	h.state = Open
+	h.cooldown = minCooldown
+	h.openUntil = now()
+	trial, err := h.allow()
+	if err != nil || !trial {
+		t.Fatalf("expected a trial: %t %v", trial, err)
+	}
+
+	// the old request finishing does not end the trial
+	h.record(old, false, 0)
+	h.abandon(old)
+	if _, err := h.allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("expected others to wait for the trial: %v", err)
+	}
+	if s := state("old-request.test"); s.State != HalfOpen {
+		t.Errorf("expected the trial to decide: %+v", s)
+	}
+
+	h.record(trial, true, 0)
+	if s := state("old-request.test"); s.State != Closed {
+		t.Errorf("expected the host to be closed: %+v", s)
+	}
+}
+
+func TestBucket(t *testing.T) {
+	advance := fakeNow(t)
+	options.Set("request_rate", 2)
+	options.Set("request_burst", 2)
+	defer options.Set("request_rate", 0)
+
+	h := lookup("bucket.test")
+	waits := []time.Duration{h.reserve(), h.reserve(), h.reserve(), h.reserve()}
+	want := []time.Duration{0, 0, 500 * time.Millisecond, time.Second}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Errorf("request %d: waited %s; want %s", i, waits[i], want[i])
+		}
+	}
+
+	// the bucket fills up again
+	advance(time.Minute)
+	if d := h.reserve(); d != 0 {
+		t.Errorf("expected a full bucket: %s", d)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		h    string
+		want time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"Sat, 02 Jan 2021 12:00:30 GMT", 30 * time.Second},
+		{"soon", 0},
+	}
+
+	for _, c := range cases {
+		if got := RetryAfter(c.h, now); got != c.want {
+			t.Errorf("RetryAfter(%q) = %s; want %s", c.h, got, c.want)
+		}
+	}
+}
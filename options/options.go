@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
@@ -47,6 +48,55 @@ func GetDuration(k string) time.Duration {
 	return v.GetDuration(k)
 }
 
+// GetBool option
+func GetBool(k string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	return v.GetBool(k)
+}
+
+// GetInt option
+func GetInt(k string) int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return v.GetInt(k)
+}
+
+// GetIntSlice option
+func GetIntSlice(k string) []int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return cast.ToIntSlice(v.Get(k))
+}
+
+// GetStringSlice option
+func GetStringSlice(k string) []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	return cast.ToStringSlice(v.Get(k))
+}
+
+// IsSet is true if an option was given a value
+func IsSet(k string) bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	return v.IsSet(k)
+}
+
+// Set an option until autosr exits
+// the config file is not changed
+func Set(k string, value interface{}) {
+	m.Lock()
+	defer m.Unlock()
+
+	v.Set(k, value)
+}
+
 const (
 	// Filename for config file
 	Filename = "autosr"
@@ -57,8 +107,13 @@ const (
 	configPathUnix          = ".config/autosr/"
 	defaultUserAgent        = `Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/71.0.3578.98 Safari/537.36`
 	defaultStreamDownloader = `streamlink --http-header User-Agent={{UserAgent}} -o {{SavePath}} {{StreamURL}} best`
-	defaultListenAddr       = ":4846"
+	defaultListenAddr       = "127.0.0.1:4846"
+	defaultShowroomAPI      = "https://www.showroom-live.com"
 	defaultPollRate         = 120 * time.Second
+	defaultPrewarmRate      = 30 * time.Second
+	defaultPrewarmLead      = 10 * time.Minute
+	defaultRequestRate      = 10
+	defaultRequestBurst     = 20
 	defaultSelectFGColor    = "blue"
 	defaultSelectBGColor    = "white"
 )
@@ -85,9 +140,21 @@ func init() {
 
 	// set defaults
 	v.SetDefault("check_every", defaultPollRate)
+	v.SetDefault("prewarm_every", defaultPrewarmRate)
+	v.SetDefault("prewarm_lead", defaultPrewarmLead)
+	v.SetDefault("adaptive_polling", true)
+	v.SetDefault("record_chat", false)
+	v.SetDefault("chat_subtitles", "")
+	v.SetDefault("showroom_use_browser", false)
+	v.SetDefault("stream_quality", "best")
+	v.SetDefault("showroom_cookies", "")
+	v.SetDefault("showroom_api", defaultShowroomAPI)
 	v.SetDefault("user_agent", defaultUserAgent)
 	v.SetDefault("download_with", defaultStreamDownloader)
 	v.SetDefault("listen_on", defaultListenAddr)
+	v.SetDefault("web_allowed_hosts", []string{})
+	v.SetDefault("request_rate", defaultRequestRate)
+	v.SetDefault("request_burst", defaultRequestBurst)
 	v.SetDefault("select_fg_color", defaultSelectFGColor)
 	v.SetDefault("select_bg_color", defaultSelectBGColor)
 
@@ -147,11 +214,15 @@ func init() {
 			if err == errInvalidPollRate {
 				v.Set("check_every", 1*time.Minute)
 			}
+			if err == errInvalidPrewarmRate {
+				v.Set("prewarm_every", defaultPrewarmRate)
+			}
 		}
 	})
 }
 
 var errInvalidPollRate = fmt.Errorf("error: time must be greater than 30s")
+var errInvalidPrewarmRate = fmt.Errorf("error: prewarm_every must be 0 or at least 10s")
 
 // AreValid is true if the options are valid
 func AreValid() (ok bool, err error) {
@@ -160,6 +231,11 @@ func AreValid() (ok bool, err error) {
 		return
 	}
 
+	if p := v.GetDuration("prewarm_every"); p != 0 && p < 10*time.Second {
+		err = errInvalidPrewarmRate
+		return
+	}
+
 	downloader := v.GetString("download_with")
 	sp := strings.Split(downloader, " ")
 	app := sp[0]
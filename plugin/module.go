@@ -0,0 +1,236 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bobbytrapz/autosr/retry"
+	"github.com/bobbytrapz/autosr/track"
+)
+
+// how long a module program has to tell us its hostname
+var helloTimeout = 10 * time.Second
+
+// Module is a module program
+type Module struct {
+	proc     *process
+	hostname string
+}
+
+func newModule(ctx context.Context, path string) (*Module, error) {
+	m := &Module{
+		proc: newProcess(ctx, path),
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, helloTimeout)
+	defer cancel()
+	if err := m.proc.call(hctx, "Hostname", struct{}{}, &m.hostname); err != nil {
+		return nil, err
+	}
+	if m.hostname == "" {
+		return nil, errors.New("plugin.newModule: no hostname given")
+	}
+
+	return m, nil
+}
+
+// Hostname given by the module program
+func (m *Module) Hostname() string {
+	return m.hostname
+}
+
+type linkParams struct {
+	Link string `json:"link"`
+}
+
+type addTargetResult struct {
+	Name     string `json:"name"`
+	Display  string `json:"display"`
+	SavePath string `json:"save_path"`
+}
+
+// AddTarget asks the module program about a link
+// a null result means the link was not accepted
+func (m *Module) AddTarget(ctx context.Context, link string) (track.Target, error) {
+	var res *addTargetResult
+	if err := m.proc.call(ctx, "AddTarget", linkParams{link}, &res); err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, nil
+	}
+
+	t := &target{
+		m:        m,
+		link:     link,
+		name:     res.Name,
+		display:  res.Display,
+		savePath: res.SavePath,
+	}
+	if t.name == "" {
+		t.name = link
+	}
+	if t.display == "" {
+		t.display = t.name
+	}
+	if t.savePath == "" {
+		t.savePath = t.name
+	}
+
+	return t, nil
+}
+
+type checkUpcomingParams struct {
+	Links []string `json:"links"`
+}
+
+type upcoming struct {
+	Link string `json:"link"`
+	Live bool   `json:"live"`
+	// null if there is no upcoming time
+	UpcomingAt *time.Time `json:"upcoming_at"`
+}
+
+// CheckUpcoming asks the module program about every target at once
+// targets that are live now are sniped now
+func (m *Module) CheckUpcoming(ctx context.Context, targets []track.Target) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	byLink := make(map[string]track.Target, len(targets))
+	params := checkUpcomingParams{}
+	for _, t := range targets {
+		byLink[t.Link()] = t
+		params.Links = append(params.Links, t.Link())
+	}
+
+	var res []upcoming
+	if err := m.proc.call(ctx, "CheckUpcoming", params, &res); err != nil {
+		return retryable(err)
+	}
+
+	for _, u := range res {
+		t, ok := byLink[u.Link]
+		if !ok {
+			continue
+		}
+
+		var at time.Time
+		if u.UpcomingAt != nil {
+			at = *u.UpcomingAt
+		}
+		if u.Live {
+			log.Println("plugin.CheckUpcoming:", t.Name(), "is live now!")
+			at = time.Now()
+		}
+		if at.IsZero() {
+			continue
+		}
+		if err := track.SnipeTargetAt(ctx, t, at); err != nil {
+			log.Println("plugin.CheckUpcoming:", err)
+		}
+	}
+
+	return nil
+}
+
+// target tracked by a module program
+type target struct {
+	m        *Module
+	link     string
+	name     string
+	display  string
+	savePath string
+}
+
+// Reload callback
+func (t *target) Reload(ctx context.Context) {}
+
+// BeginSnipe callback
+func (t *target) BeginSnipe(ctx context.Context) {}
+
+// BeginSave callback
+func (t *target) BeginSave(ctx context.Context) {}
+
+// EndSave callback
+func (t *target) EndSave(ctx context.Context) {}
+
+// Display for display in dashboard
+func (t *target) Display() string {
+	return t.display
+}
+
+// Name is the streamers real name
+func (t *target) Name() string {
+	return t.name
+}
+
+// Link is url string where this user's streams can be found
+func (t *target) Link() string {
+	return t.link
+}
+
+// SavePath decides where videos are saved
+func (t *target) SavePath() string {
+	return t.savePath
+}
+
+// CheckLive gives true if the user is online
+func (t *target) CheckLive(ctx context.Context) (isLive bool, err error) {
+	err = t.m.proc.call(ctx, "CheckLive", linkParams{t.link}, &isLive)
+	if err == nil && !isLive {
+		return false, retry.Temporary(fmt.Errorf("plugin.CheckLive: %s is %w", t.name, track.ErrNotLive))
+	}
+
+	return isLive, retryable(err)
+}
+
+type checkStreamResult struct {
+	URL            string `json:"url"`
+	TicketRequired bool   `json:"ticket_required"`
+}
+
+// CheckStream gives the stream url the module program found
+func (t *target) CheckStream(ctx context.Context) (streamURL string, err error) {
+	var res checkStreamResult
+	err = t.m.proc.call(ctx, "CheckStream", linkParams{t.link}, &res)
+	if err == nil {
+		if res.TicketRequired {
+			return "", fmt.Errorf("plugin.CheckStream: %s: %w", t.name, track.ErrTicketRequired)
+		}
+		if res.URL != "" {
+			return res.URL, nil
+		}
+		err = Error{Method: "CheckStream", Message: fmt.Sprintf("%s has no stream yet", t.name), Retry: true}
+	}
+
+	return "", retryable(err)
+}
+
+// retryable marks errors the module program says may pass if we try again
+func retryable(err error) error {
+	var perr Error
+	if errors.As(err, &perr) && perr.Retry {
+		return retry.Temporary(err)
+	}
+	return err
+}
@@ -0,0 +1,303 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package plugin runs modules as separate programs
+//
+// Each executable in the 'modules' directory of the config path is a module.
+// autosr writes one JSON request per line to its stdin
+//
+//	{"id": 1, "method": "CheckStream", "params": {"link": "https://example.com/someone"}}
+//
+// and it writes one JSON response per line to its stdout with the same id
+//
+//	{"id": 1, "result": {"url": "https://example.com/someone/index.m3u8"}}
+//
+// A failed request gives "error" and sets "retry" if it is worth trying again.
+// Requests may be sent before earlier ones are answered.
+// Anything written to stderr goes to the autosr log.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/backoff"
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/retry"
+	"github.com/bobbytrapz/autosr/track"
+)
+
+// Dir is where module programs are found
+var Dir = filepath.Join(options.ConfigPath, "modules")
+
+type request struct {
+	ID     int         `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+type response struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+	Retry  bool            `json:"retry"`
+}
+
+// Error is given by a module program
+type Error struct {
+	Method  string
+	Message string
+	// the module thinks it is worth trying again
+	Retry bool
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("plugin.%s: %s", e.Method, e.Message)
+}
+
+var errExited = errors.New("module program exited")
+
+// a module program that takes longer than this to answer is given up on
+var callTimeout = 1 * time.Minute
+
+// process talks to one module program
+// the program is started again if it exits
+type process struct {
+	// the program runs until this is done
+	ctx  context.Context
+	path string
+
+	mu      sync.Mutex
+	stdin   io.WriteCloser
+	pending map[int]chan response
+	nextID  int
+	running bool
+
+	// a program that keeps exiting is not started again right away
+	restarts  *backoff.Backoff
+	restartAt time.Time
+}
+
+func newProcess(ctx context.Context, path string) *process {
+	p := &process{
+		ctx:     ctx,
+		path:    path,
+		pending: make(map[int]chan response),
+	}
+	p.restarts = backoff.For(p.name(), backoff.Recover).New()
+
+	return p
+}
+
+func (p *process) name() string {
+	return filepath.Base(p.path)
+}
+
+// must hold p.mu
+func (p *process) start() error {
+	cmd := exec.CommandContext(p.ctx, p.path)
+	cmd.Dir = filepath.Dir(p.path)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	log.Println("plugin.start:", p.name(), cmd.Process.Pid)
+	startedAt := time.Now()
+
+	p.stdin = stdin
+	p.running = true
+
+	go func() {
+		s := bufio.NewScanner(stderr)
+		for s.Scan() {
+			log.Printf("plugin: %s: %s\n", p.name(), s.Text())
+		}
+	}()
+
+	go func() {
+		s := bufio.NewScanner(stdout)
+		s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for s.Scan() {
+			var res response
+			if err := json.Unmarshal(s.Bytes(), &res); err != nil {
+				log.Printf("plugin: %s: invalid response: %s\n", p.name(), err)
+				continue
+			}
+			p.mu.Lock()
+			ch, ok := p.pending[res.ID]
+			delete(p.pending, res.ID)
+			p.mu.Unlock()
+			if ok {
+				ch <- res
+			}
+		}
+
+		err := cmd.Wait()
+		log.Println("plugin:", p.name(), "exited:", err)
+
+		// nobody will answer what is left
+		p.mu.Lock()
+		p.running = false
+		// a program that ran for a while is not crashing over and over
+		if time.Since(startedAt) > callTimeout {
+			p.restarts.Reset()
+		}
+		p.restartAt = time.Now().Add(p.restarts.Next())
+		for id, ch := range p.pending {
+			delete(p.pending, id)
+			close(ch)
+		}
+		p.mu.Unlock()
+	}()
+
+	return nil
+}
+
+// call a method and decode the result into res
+func (p *process) call(ctx context.Context, method string, params interface{}, res interface{}) error {
+	p.mu.Lock()
+	if !p.running {
+		if wait := time.Until(p.restartAt); wait > 0 {
+			p.mu.Unlock()
+			err := Error{Method: method, Message: fmt.Sprintf("%s is starting again in %s", p.name(), wait.Truncate(time.Millisecond)), Retry: true}
+			return retry.After(err, wait)
+		}
+		if err := p.start(); err != nil {
+			p.mu.Unlock()
+			return fmt.Errorf("plugin.call: %s: %s", p.name(), err)
+		}
+	}
+	p.nextID++
+	id := p.nextID
+	ch := make(chan response, 1)
+	p.pending[id] = ch
+
+	line, err := json.Marshal(request{ID: id, Method: method, Params: params})
+	if err == nil {
+		_, err = p.stdin.Write(append(line, '\n'))
+	}
+	if err != nil {
+		delete(p.pending, id)
+		p.mu.Unlock()
+		return fmt.Errorf("plugin.call: %s: %s", p.name(), err)
+	}
+	p.mu.Unlock()
+
+	to := time.NewTimer(callTimeout)
+	defer to.Stop()
+
+	select {
+	case r, ok := <-ch:
+		if !ok {
+			return Error{Method: method, Message: errExited.Error(), Retry: true}
+		}
+		if r.Error != "" {
+			return Error{Method: method, Message: r.Error, Retry: r.Retry}
+		}
+		if res == nil || len(r.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(r.Result, res); err != nil {
+			return fmt.Errorf("plugin.call: %s: %s: %s", p.name(), method, err)
+		}
+		return nil
+	case <-to.C:
+		p.forget(id)
+		return Error{Method: method, Message: "timeout", Retry: true}
+	case <-ctx.Done():
+		p.forget(id)
+		return ctx.Err()
+	}
+}
+
+func (p *process) forget(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, id)
+}
+
+// gives true if a file looks like a program we can run
+func isExecutable(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(info.Name())) {
+		case ".exe", ".bat", ".cmd":
+			return true
+		}
+		return false
+	}
+
+	return info.Mode()&0111 != 0
+}
+
+// Load starts each module program and registers it as a module
+// a program that does not answer is skipped
+// programs are stopped when ctx is done
+func Load(ctx context.Context) error {
+	entries, err := os.ReadDir(Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("plugin.Load: %s", err)
+	}
+
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !isExecutable(info) {
+			continue
+		}
+
+		m, err := newModule(ctx, filepath.Join(Dir, e.Name()))
+		if err != nil {
+			log.Println("plugin.Load:", e.Name(), err)
+			continue
+		}
+		if err := track.RegisterModule(m); err != nil {
+			log.Println("plugin.Load:", e.Name(), err)
+			continue
+		}
+		log.Println("plugin.Load:", e.Name(), "is", m.Hostname())
+	}
+
+	return nil
+}
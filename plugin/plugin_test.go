@@ -0,0 +1,185 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/retry"
+	"github.com/bobbytrapz/autosr/track"
+)
+
+const helperEnv = "AUTOSR_TEST_PLUGIN"
+
+// the test binary acts as a module program when asked
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) != "" {
+		fakeModule()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func fakeModule() {
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		var req struct {
+			ID     int
+			Method string
+			Params struct {
+				Link  string
+				Links []string
+			}
+		}
+		if err := json.Unmarshal(s.Bytes(), &req); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		res := map[string]interface{}{"id": req.ID}
+		link := req.Params.Link
+		switch req.Method {
+		case "Hostname":
+			res["result"] = "example.com"
+		case "AddTarget":
+			if strings.Contains(link, "nobody") {
+				res["result"] = nil
+			} else {
+				res["result"] = map[string]string{"name": "someone"}
+			}
+		case "Exit":
+			os.Exit(1)
+		case "CheckLive":
+			res["result"] = !strings.Contains(link, "offline")
+		case "CheckStream":
+			switch {
+			case strings.Contains(link, "ticket"):
+				res["result"] = map[string]bool{"ticket_required": true}
+			case strings.Contains(link, "offline"):
+				res["result"] = map[string]string{}
+			case strings.Contains(link, "broken"):
+				res["error"] = "the site changed"
+			default:
+				res["result"] = map[string]string{"url": link + "/index.m3u8"}
+			}
+		default:
+			res["error"] = "unknown method"
+		}
+
+		line, _ := json.Marshal(res)
+		fmt.Println(string(line))
+	}
+}
+
+func startFake(t *testing.T) *Module {
+	t.Setenv(helperEnv, "1")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	m, err := newModule(ctx, os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestModule(t *testing.T) {
+	m := startFake(t)
+	ctx := context.Background()
+
+	if m.Hostname() != "example.com" {
+		t.Errorf("hostname: %q", m.Hostname())
+	}
+
+	if tt, err := m.AddTarget(ctx, "https://example.com/nobody"); err != nil || tt != nil {
+		t.Errorf("expected the link to not be accepted: %v %v", tt, err)
+	}
+
+	tt, err := m.AddTarget(ctx, "https://example.com/someone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tt.Name() != "someone" || tt.Display() != "someone" || tt.SavePath() != "someone" {
+		t.Errorf("unexpected target: %q %q %q", tt.Name(), tt.Display(), tt.SavePath())
+	}
+
+	if isLive, err := tt.CheckLive(ctx); err != nil || !isLive {
+		t.Errorf("expected live: %v %v", isLive, err)
+	}
+	streamURL, err := tt.CheckStream(ctx)
+	if err != nil || streamURL != "https://example.com/someone/index.m3u8" {
+		t.Errorf("unexpected stream: %q %v", streamURL, err)
+	}
+}
+
+func TestCheckStreamErrors(t *testing.T) {
+	m := startFake(t)
+	ctx := context.Background()
+
+	check := func(link string) error {
+		tt, err := m.AddTarget(ctx, link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tt.CheckStream(ctx)
+		return err
+	}
+
+	if err := check("https://example.com/ticket"); !errors.Is(err, track.ErrTicketRequired) {
+		t.Errorf("expected a ticket to be required: %v", err)
+	}
+	if !retry.IsTemporary(check("https://example.com/offline")) {
+		t.Error("expected no stream yet to be retryable")
+	}
+	err := check("https://example.com/broken")
+	if retry.IsTemporary(err) || err == nil {
+		t.Errorf("expected a module error to not be retryable: %v", err)
+	}
+}
+
+func TestRestartDelay(t *testing.T) {
+	// the test binary is the module program so its name picks the policy
+	options.Set("backoff.plugin.recover.kind", "fixed")
+	options.Set("backoff.plugin.recover.base", "1h")
+	m := startFake(t)
+	ctx := context.Background()
+
+	if err := m.proc.call(ctx, "Exit", struct{}{}, nil); err == nil {
+		t.Fatal("expected the program to exit")
+	}
+
+	// it is not started again right away
+	err := m.proc.call(ctx, "Hostname", struct{}{}, nil)
+	if d := retry.Delay(err); d < 59*time.Minute {
+		t.Errorf("expected to wait before starting again: %v %s", err, d)
+	}
+
+	m.proc.mu.Lock()
+	m.proc.restartAt = time.Now()
+	m.proc.mu.Unlock()
+	if err := m.proc.call(ctx, "Hostname", struct{}{}, nil); err != nil {
+		t.Errorf("expected the program to start again: %v", err)
+	}
+}
@@ -13,30 +13,175 @@
 // You should have received a copy of the GNU General Public License
 // along with autosr.  If not, see <https://www.gnu.org/licenses/>.
 
+// Package retry tries again when something fails for a reason that may pass
 package retry
 
-// Retryable errors can be retried if the caller wants
-type Retryable interface {
-	Retry() error
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bobbytrapz/autosr/backoff"
+)
+
+// ErrTimeout is given when Options.Timeout fires before we succeed
+var ErrTimeout = errors.New("retry: timeout")
+
+type temporary struct {
+	err error
+}
+
+func (e temporary) Error() string {
+	return e.err.Error()
+}
+
+func (e temporary) Unwrap() error {
+	return e.err
 }
 
-// Check will give a Retryable and true if we can try again
-func Check(err error) (Retryable, bool) {
-	t, ok := err.(Retryable)
-	return t, ok
+type permanent struct {
+	err error
 }
 
-// Error is an error we can retry
-type Error struct {
-	Message string
-	Attempt func() error
+func (e permanent) Error() string {
+	return e.err.Error()
 }
 
-func (e Error) Error() string {
-	return e.Message
+func (e permanent) Unwrap() error {
+	return e.err
 }
 
-// Retry makes another attempt
-func (e Error) Retry() error {
-	return e.Attempt()
+// waits at least d before trying again
+type delayed struct {
+	err error
+	d   time.Duration
+}
+
+func (e delayed) Error() string {
+	return e.err.Error()
+}
+
+func (e delayed) Unwrap() error {
+	return e.err
+}
+
+// Temporary marks an error that may pass if we try again
+func Temporary(err error) error {
+	if err == nil {
+		return nil
+	}
+	return temporary{err}
+}
+
+// Permanent marks an error that will not pass no matter how often we try
+// it wins over Temporary anywhere in the chain
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanent{err}
+}
+
+// After marks a temporary error that should not be tried again for at least d
+// such as when a server gives Retry-After
+func After(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return delayed{err, d}
+}
+
+// Delay gives how long an error asked us to wait before trying again
+func Delay(err error) time.Duration {
+	var e delayed
+	if errors.As(err, &e) {
+		return e.d
+	}
+	return 0
+}
+
+// IsTemporary is true if an error was marked Temporary and not Permanent
+// a canceled context is never temporary
+func IsTemporary(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var p permanent
+	if errors.As(err, &p) {
+		return false
+	}
+	var t temporary
+	var d delayed
+	return errors.As(err, &t) || errors.As(err, &d)
+}
+
+// Options for Do
+type Options struct {
+	// give up after this many attempts
+	// zero keeps trying until the context is done or Timeout fires
+	MaxAttempts int
+	// how long to wait before each attempt after the first
+	// DefaultPolicy if nil. an error given by After may ask us to wait longer.
+	Policy *backoff.Policy
+	// decides if an error is worth trying again
+	// IsTemporary if nil
+	Retryable func(error) bool
+	// called after each failed attempt with the attempt number starting at 1
+	OnAttempt func(attempt int, err error)
+	// waits between attempts
+	// time.After if nil. tests give a fake clock here.
+	After func(time.Duration) <-chan time.Time
+	// give up with ErrTimeout when this fires
+	Timeout <-chan time.Time
+}
+
+// Do calls attempt until it succeeds, fails for good or we give up
+// the last error is given when we give up
+func Do[T any](ctx context.Context, opts Options, attempt func(context.Context) (T, error)) (res T, err error) {
+	policy := opts.Policy
+	if policy == nil {
+		policy = &backoff.DefaultPolicy
+	}
+	retryable := opts.Retryable
+	if retryable == nil {
+		retryable = IsTemporary
+	}
+	after := opts.After
+	if after == nil {
+		after = time.After
+	}
+
+	b := policy.New()
+	for n := 0; ; n++ {
+		if n > 0 {
+			wait := b.Next()
+			if d := Delay(err); d > wait {
+				wait = d
+			}
+			select {
+			case <-ctx.Done():
+				return res, ctx.Err()
+			case <-opts.Timeout:
+				return res, ErrTimeout
+			case <-after(wait):
+			}
+		}
+
+		res, err = attempt(ctx)
+		if err == nil {
+			return
+		}
+		if opts.OnAttempt != nil {
+			opts.OnAttempt(n+1, err)
+		}
+		if !retryable(err) {
+			return
+		}
+		if opts.MaxAttempts > 0 && n+1 >= opts.MaxAttempts {
+			return
+		}
+	}
 }
@@ -0,0 +1,152 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bobbytrapz/autosr/backoff"
+)
+
+var noWait = &backoff.Policy{Steps: []int{0}}
+
+func TestIsTemporary(t *testing.T) {
+	base := errors.New("offline")
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{base, false},
+		{Temporary(base), true},
+		{fmt.Errorf("wrapped: %w", Temporary(base)), true},
+		{Permanent(Temporary(base)), false},
+		{Temporary(Permanent(base)), false},
+		{Temporary(context.Canceled), false},
+	}
+
+	for _, c := range cases {
+		if got := IsTemporary(c.err); got != c.want {
+			t.Errorf("IsTemporary(%v) = %v; want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	base := errors.New("not yet")
+
+	calls := 0
+	var attempts []int
+	got, err := Do(ctx, Options{
+		Policy: noWait,
+		OnAttempt: func(n int, err error) {
+			attempts = append(attempts, n)
+		},
+	}, func(context.Context) (string, error) {
+		calls++
+		if calls < 3 {
+			return "", Temporary(base)
+		}
+		return "done", nil
+	})
+	if err != nil || got != "done" {
+		t.Errorf("got %q %v", got, err)
+	}
+	if len(attempts) != 2 || attempts[1] != 2 {
+		t.Errorf("unexpected attempts: %v", attempts)
+	}
+
+	// permanent errors are not tried again
+	calls = 0
+	_, err = Do(ctx, Options{Policy: noWait}, func(context.Context) (int, error) {
+		calls++
+		return 0, base
+	})
+	if calls != 1 || err != base {
+		t.Errorf("expected one attempt: %d %v", calls, err)
+	}
+
+	// give up after MaxAttempts with the last error
+	calls = 0
+	_, err = Do(ctx, Options{Policy: noWait, MaxAttempts: 3}, func(context.Context) (bool, error) {
+		calls++
+		return false, Temporary(base)
+	})
+	if calls != 3 || !errors.Is(err, base) {
+		t.Errorf("expected 3 attempts: %d %v", calls, err)
+	}
+}
+
+func TestDoTimeout(t *testing.T) {
+	timeout := make(chan time.Time)
+	wait := make(chan time.Time)
+	close(timeout)
+
+	_, err := Do(context.Background(), Options{
+		Timeout: timeout,
+		After: func(time.Duration) <-chan time.Time {
+			return wait
+		},
+	}, func(context.Context) (int, error) {
+		return 0, Temporary(errors.New("not yet"))
+	})
+	if err != ErrTimeout {
+		t.Errorf("expected a timeout: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Do(ctx, Options{}, func(context.Context) (int, error) {
+		return 0, Temporary(errors.New("not yet"))
+	})
+	if err != context.Canceled {
+		t.Errorf("expected canceled: %v", err)
+	}
+}
+
+func TestDoDelay(t *testing.T) {
+	var waits []time.Duration
+	calls := 0
+	_, err := Do(context.Background(), Options{
+		Policy: noWait,
+		After: func(d time.Duration) <-chan time.Time {
+			waits = append(waits, d)
+			c := make(chan time.Time, 1)
+			c <- time.Time{}
+			return c
+		},
+	}, func(context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, fmt.Errorf("wrapped: %w", After(errors.New("slow down"), 5*time.Second))
+		}
+		if calls == 2 {
+			return 0, Temporary(errors.New("not yet"))
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(waits) != 2 || waits[0] != 5*time.Second || waits[1] != 0 {
+		t.Errorf("expected to wait 5s once: %v", waits)
+	}
+}
@@ -0,0 +1,213 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/track"
+)
+
+// comments stay on screen this long in subtitles
+const subtitleDuration = 5 * time.Second
+
+// a line in the chat log
+type chatEntry struct {
+	Type string    `json:"type"`
+	At   time.Time `json:"at"`
+	// seconds since the recording began
+	Offset float64 `json:"offset"`
+	User   string  `json:"user"`
+	UserID int     `json:"user_id"`
+	Text   string  `json:"text,omitempty"`
+	GiftID int     `json:"gift_id,omitempty"`
+	Amount int     `json:"amount,omitempty"`
+}
+
+// writes chat next to a recording
+type chatRecorder struct {
+	sync.Mutex
+	startedAt time.Time
+	log       *os.File
+	subs      *os.File
+	w         *bufio.Writer
+	sw        *bufio.Writer
+	format    string
+	numSubs   int
+}
+
+// subtitles may be "srt", "ass" or empty for none
+func newChatRecorder(saveAs string, startedAt time.Time, subtitles string) (*chatRecorder, error) {
+	r := &chatRecorder{
+		startedAt: startedAt,
+		format:    strings.ToLower(subtitles),
+	}
+
+	var err error
+	r.log, err = os.OpenFile(track.SidecarPath(saveAs, ".chat.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("showroom.newChatRecorder: %s", err)
+	}
+	r.w = bufio.NewWriter(r.log)
+
+	switch r.format {
+	case "":
+		return r, nil
+	case "srt", "ass":
+	default:
+		r.log.Close()
+		return nil, fmt.Errorf("showroom.newChatRecorder: unknown subtitle format: %q", subtitles)
+	}
+
+	r.subs, err = os.OpenFile(track.SidecarPath(saveAs, "."+r.format), os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		r.log.Close()
+		return nil, fmt.Errorf("showroom.newChatRecorder: %s", err)
+	}
+	r.sw = bufio.NewWriter(r.subs)
+
+	if r.format == "ass" {
+		fmt.Fprint(r.sw, assHeader)
+	}
+
+	return r, nil
+}
+
+// Record a comment or gift
+func (r *chatRecorder) Record(event interface{}) error {
+	var e chatEntry
+	switch v := event.(type) {
+	case Comment:
+		e = chatEntry{
+			Type:   "comment",
+			At:     v.At,
+			User:   v.Name,
+			UserID: v.User.ID,
+			Text:   v.Text,
+		}
+	case Gift:
+		e = chatEntry{
+			Type:   "gift",
+			At:     v.At,
+			User:   v.Name,
+			UserID: v.User.ID,
+			GiftID: v.ID,
+			Amount: v.Amount,
+		}
+	default:
+		return nil
+	}
+	e.Offset = e.At.Sub(r.startedAt).Seconds()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("showroom.chatRecorder.Record: %s", err)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.w.Write(data)
+	r.w.WriteByte('\n')
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("showroom.chatRecorder.Record: %s", err)
+	}
+
+	// only comments are shown as subtitles
+	if r.sw == nil || e.Type != "comment" {
+		return nil
+	}
+
+	begin := e.At.Sub(r.startedAt)
+	if begin < 0 {
+		begin = 0
+	}
+	end := begin + subtitleDuration
+	text := fmt.Sprintf("%s: %s", e.User, e.Text)
+	r.numSubs++
+
+	switch r.format {
+	case "srt":
+		fmt.Fprintf(r.sw, "%d\n%s --> %s\n%s\n\n", r.numSubs, srtTime(begin), srtTime(end), text)
+	case "ass":
+		text = strings.NewReplacer("\n", `\N`, "{", "(", "}", ")").Replace(text)
+		fmt.Fprintf(r.sw, "Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n", assTime(begin), assTime(end), text)
+	}
+
+	if err := r.sw.Flush(); err != nil {
+		return fmt.Errorf("showroom.chatRecorder.Record: %s", err)
+	}
+
+	return nil
+}
+
+// Close the chat files
+func (r *chatRecorder) Close() error {
+	r.Lock()
+	defer r.Unlock()
+
+	err := r.w.Flush()
+	if cerr := r.log.Close(); err == nil {
+		err = cerr
+	}
+	if r.subs != nil {
+		if ferr := r.sw.Flush(); err == nil {
+			err = ferr
+		}
+		if cerr := r.subs.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// 01:02:03,456
+func srtTime(d time.Duration) string {
+	h := d / time.Hour
+	m := d % time.Hour / time.Minute
+	s := d % time.Minute / time.Second
+	ms := d % time.Second / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, ms)
+}
+
+// 1:02:03.45
+func assTime(d time.Duration) string {
+	h := d / time.Hour
+	m := d % time.Hour / time.Minute
+	s := d % time.Minute / time.Second
+	cs := d % time.Second / (10 * time.Millisecond)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", h, m, s, cs)
+}
+
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 1280
+PlayResY: 720
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,28,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,0,1,20,20,20,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
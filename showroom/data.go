@@ -16,8 +16,8 @@
 package showroom
 
 import (
+	"fmt"
 	"net/url"
-	"strings"
 	"time"
 )
 
@@ -27,6 +27,68 @@ type onlivesResponse struct {
 	Onlives []livesData `json:"onlives"`
 }
 
+// details about the current live from the live_info api
+type liveInfoResponse struct {
+	LiveID     int    `json:"live_id"`
+	LiveStatus int    `json:"live_status"`
+	RoomID     int    `json:"room_id"`
+	RoomName   string `json:"room_name"`
+	BcsvrKey   string `json:"bcsvr_key"`
+	BcsvrHost  string `json:"bcsvr_host"`
+	BcsvrPort  int    `json:"bcsvr_port"`
+}
+
+// the broadcast server sends comments, gifts and telop changes for a live
+type broadcastServer struct {
+	Host string
+	Port int
+	Key  string
+}
+
+// websocket url for the broadcast server
+func (b broadcastServer) url() url.URL {
+	host := b.Host
+	if host == "" {
+		host = bcsvrHost
+	}
+
+	// the secure websocket is always on 443
+	if b.Port == 0 || b.Port == 443 {
+		return url.URL{Scheme: "wss", Host: host}
+	}
+
+	return url.URL{Scheme: "ws", Host: fmt.Sprintf("%s:%d", host, b.Port)}
+}
+
+// room/status api
+type roomStatusResponse struct {
+	ID        int    `json:"room_id"`
+	URLKey    string `json:"room_url_key"`
+	Name      string `json:"room_name"`
+	IsLive    bool   `json:"is_live"`
+	StartedAt int64  `json:"started_at"`
+	LiveID    int    `json:"live_id"`
+}
+
+// live/telop api
+type telopResponse struct {
+	Telop string `json:"telop"`
+}
+
+// room/profile api
+type roomProfileResponse struct {
+	Name            string `json:"room_name"`
+	URLKey          string `json:"room_url_key"`
+	MainName        string `json:"main_name"`
+	IsOnlive        bool   `json:"is_onlive"`
+	StartedAt       int64  `json:"current_live_started_at"`
+	LiveID          int    `json:"live_id"`
+	FollowerNum     int    `json:"follower_num"`
+	GenreName       string `json:"genre_name"`
+	Image           string `json:"image"`
+	PremiumRoomType int    `json:"premium_room_type"`
+}
+
 type isLiveResponse struct {
 	Ok int `json:"ok"`
 }
@@ -66,6 +128,8 @@ type room struct {
 	StreamingURLs []stream `json:"streaming_url_list"`
 	Telop         string   `json:"telop"`
 	ViewNum       int      `json:"view_num"`
+	// from the genre the room was listed in
+	GenreName string `json:"genre_name"`
 	// internal
 	lastStatusAt time.Time
 	lastStatus   roomStatus
@@ -116,11 +180,3 @@ type stream struct {
 	URL     string `json:"url"`
 	Quality int    `json:"quality"`
 }
-
-func (s *stream) low() string {
-	return s.URL
-}
-
-func (s *stream) high() string {
-	return strings.Replace(s.URL, "_low", "", 1)
-}
@@ -0,0 +1,274 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/bobbytrapz/autosr/track"
+)
+
+// how often we look for a new telop while saving
+// we only ask when we are not connected to the broadcast server
+var telopEvery = time.Minute
+
+// details about a live kept in the save metadata
+type liveDetails struct {
+	LiveID      int    `json:"live_id,omitempty"`
+	Telop       string `json:"telop"`
+	GenreID     int    `json:"genre_id,omitempty"`
+	GenreName   string `json:"genre_name,omitempty"`
+	FollowerNum int    `json:"follower_num,omitempty"`
+	ViewNum     int    `json:"view_num,omitempty"`
+	StartedAt   int64  `json:"started_at,omitempty"`
+	Image       string `json:"image,omitempty"`
+	Thumbnail   string `json:"thumbnail,omitempty"`
+	// every telop shown while we saved
+	Titles []titleChange `json:"titles,omitempty"`
+	// when we began saving
+	recordingAt time.Time
+}
+
+// a telop that was shown from some point in a recording
+type titleChange struct {
+	At time.Time `json:"at"`
+	// seconds from the start of the recording
+	Offset float64 `json:"offset"`
+	Telop  string  `json:"telop"`
+}
+
+// a part of a recording with one title
+type chapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end,omitempty"`
+	Title string  `json:"title"`
+}
+
+// gives a change of title and gives true if it is different
+func (d *liveDetails) changeTitle(telop string, at time.Time) bool {
+	if len(d.Titles) > 0 && d.Titles[len(d.Titles)-1].Telop == telop {
+		return false
+	}
+
+	offset := at.Sub(d.recordingAt).Seconds()
+	if offset < 0 {
+		offset = 0
+	}
+	d.Telop = telop
+	d.Titles = append(d.Titles, titleChange{
+		At:     at,
+		Offset: offset,
+		Telop:  telop,
+	})
+
+	return true
+}
+
+// chapter markers from the title timeline
+// the last chapter lasts until the end of the recording
+func (d *liveDetails) chapters() (chapters []chapter) {
+	for ndx, title := range d.Titles {
+		c := chapter{
+			Start: title.Offset,
+			Title: title.Telop,
+		}
+		if ndx+1 < len(d.Titles) {
+			c.End = d.Titles[ndx+1].Offset
+		}
+		chapters = append(chapters, c)
+	}
+
+	return
+}
+
+// takes what we know from the list of live rooms
+func (d *liveDetails) fromRoom(r room) {
+	d.LiveID = r.LiveID
+	d.Telop = r.Telop
+	d.GenreID = r.GenreID
+	d.GenreName = r.GenreName
+	d.FollowerNum = r.FollowerNum
+	d.ViewNum = r.ViewNum
+	d.StartedAt = r.StartedAt
+	d.Image = r.ImageLive
+	if d.Image == "" {
+		d.Image = r.Image
+	}
+}
+
+// takes what we know from the room profile
+func (d *liveDetails) fromProfile(p roomProfileResponse) {
+	d.LiveID = p.LiveID
+	d.GenreName = p.GenreName
+	d.FollowerNum = p.FollowerNum
+	d.StartedAt = p.StartedAt
+	d.Image = p.Image
+}
+
+func (t *target) liveDetails() liveDetails {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	d := t.details
+	// the timeline keeps growing while we save
+	d.Titles = append([]titleChange(nil), d.Titles...)
+	return d
+}
+
+func (t *target) setLiveDetails(d liveDetails) {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	t.details = d
+}
+
+// Title is the current telop while we save
+func (t *target) Title() string {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	if t.detailsCancel == nil {
+		return ""
+	}
+	return t.details.Telop
+}
+
+// adds a telop to the timeline and writes the save metadata if it changed
+func (t *target) updateTelop(telop string, at time.Time) {
+	t.liveMu.Lock()
+	changed := t.details.changeTitle(telop, at)
+	t.liveMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	log.Printf("showroom.updateTelop: %s %q\n", t.name, telop)
+	if err := track.UpdateMetadata(t.link); err != nil {
+		log.Println("showroom.updateTelop:", err)
+	}
+}
+
+// find out about the live we are about to save
+func (t *target) checkLiveDetails(ctx context.Context) (d liveDetails) {
+	if r := t.liveRoom(); r.ID == t.id && r.LiveID != 0 {
+		d.fromRoom(r)
+	} else if p, err := checkRoomProfile(ctx, t.id); err == nil {
+		d.fromProfile(p)
+	} else {
+		log.Println("showroom.checkLiveDetails:", t.name, err)
+	}
+
+	if d.Telop == "" {
+		if telop, err := checkTelop(ctx, t.id); err == nil {
+			d.Telop = telop
+		} else {
+			log.Println("showroom.checkLiveDetails:", t.name, err)
+		}
+	}
+
+	d.recordingAt = time.Now()
+	if telop := d.Telop; telop != "" {
+		d.Telop = ""
+		d.changeTitle(telop, d.recordingAt)
+	}
+
+	return
+}
+
+// keep the live details up to date while we save
+// the thumbnail is saved next to the recording
+func (t *target) watchLiveDetails(ctx context.Context, saveAs string) <-chan struct{} {
+	done := make(chan struct{})
+
+	track.Add(1)
+	go func() {
+		defer track.Done()
+		defer close(done)
+
+		if d := t.liveDetails(); d.Image != "" {
+			p := track.SidecarPath(saveAs, ".jpg")
+			if err := saveThumbnail(ctx, d.Image, p); err != nil {
+				log.Println("showroom.watchLiveDetails:", t.name, err)
+			} else {
+				t.liveMu.Lock()
+				t.details.Thumbnail = p
+				t.liveMu.Unlock()
+				_ = track.UpdateMetadata(t.link)
+			}
+		}
+
+		tick := time.NewTicker(telopEvery)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+			}
+
+			// the list of live rooms is shared so this is usually free
+			if rooms, _, err := liveRooms(ctx); err == nil {
+				if r, ok := rooms[t.id]; ok {
+					t.liveMu.Lock()
+					t.details.ViewNum = r.ViewNum
+					t.liveMu.Unlock()
+				}
+			}
+
+			// the broadcast server tells us when the telop changes
+			if t.eventsConnected() {
+				continue
+			}
+
+			telop, err := checkTelop(ctx, t.id)
+			if err != nil {
+				log.Println("showroom.watchLiveDetails:", t.name, err)
+				continue
+			}
+			t.updateTelop(telop, time.Now())
+		}
+	}()
+
+	return done
+}
+
+// download a thumbnail image
+func saveThumbnail(ctx context.Context, link, saveAs string) error {
+	req, err := makeRequest(ctx, "GET", link, nil, "")
+	if err != nil {
+		return fmt.Errorf("showroom.saveThumbnail: %s", err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("showroom.saveThumbnail: %s", err)
+	}
+	defer res.Body.Close()
+
+	buf, err := readResponse(res)
+	if err != nil {
+		return fmt.Errorf("showroom.saveThumbnail: %s", err)
+	}
+
+	if err := ioutil.WriteFile(saveAs, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("showroom.saveThumbnail: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTelop(t *testing.T) {
+	msg := []byte("MSG\t59eb86:iTW7lR5i\t{\"telop\":\"singing\",\"t\":8,\"created_at\":1547732326}")
+	ev, err := parseEvent(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	telop, ok := ev.(Telop)
+	if !ok {
+		t.Fatalf("expected a telop: %#v", ev)
+	}
+	if telop.Text != "singing" || !telop.At.Equal(time.Unix(1547732326, 0)) {
+		t.Errorf("unexpected telop: %#v", telop)
+	}
+}
+
+func TestChapters(t *testing.T) {
+	start := time.Date(2020, 1, 1, 20, 0, 0, 0, time.UTC)
+	d := liveDetails{recordingAt: start}
+
+	if !d.changeTitle("hello", start.Add(-time.Minute)) {
+		t.Error("the first title is a change")
+	}
+	if d.changeTitle("hello", start.Add(time.Minute)) {
+		t.Error("the same title is not a change")
+	}
+	d.changeTitle("singing", start.Add(10*time.Minute))
+	d.changeTitle("goodbye", start.Add(30*time.Minute))
+
+	want := []chapter{
+		{Start: 0, End: 600, Title: "hello"},
+		{Start: 600, End: 1800, Title: "singing"},
+		{Start: 1800, Title: "goodbye"},
+	}
+	got := d.chapters()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d chapters: %v", len(want), got)
+	}
+	for ndx := range want {
+		if got[ndx] != want[ndx] {
+			t.Errorf("chapter %d: expected %v got %v", ndx, want[ndx], got[ndx])
+		}
+	}
+	if d.Telop != "goodbye" {
+		t.Errorf("expected the current telop to be the last title: %q", d.Telop)
+	}
+}
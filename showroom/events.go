@@ -22,8 +22,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"net/url"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bobbytrapz/autosr/options"
@@ -58,21 +58,54 @@ type Gift struct {
 	Amount int
 }
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+// Telop is the text shown over the stream
+// it is used as the title of a live
+type Telop struct {
+	Event
+	Text string
 }
 
 const (
-	bcsvrHost = "online.showroom-live.com:443"
+	bcsvrHost = "online.showroom-live.com"
 )
 
-// channel for sending message to chat server
-var msgSend chan []byte
+// a number the broadcast server sometimes sends as a string
+type eventNumber struct {
+	n  int64
+	ok bool
+}
 
-type chatConnection struct {
-	bcsvrKey  string // "59f5e9:vF3aKm5p"
-	startedAt time.Time
+func (e *eventNumber) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(strings.Trim(string(b), `"`), 64)
+	if err != nil {
+		return fmt.Errorf("not a number: %s", b)
+	}
+	e.n, e.ok = int64(f), true
+	return nil
+}
+
+type eventData struct {
+	Type      eventNumber `json:"t"`
+	Account   *string     `json:"ac"`
+	UserID    eventNumber `json:"u"`
+	CreatedAt eventNumber `json:"created_at"`
+	Comment   *string     `json:"cm"`
+	GiftID    eventNumber `json:"g"`
+	Amount    eventNumber `json:"n"`
+	Telop     string      `json:"telop"`
+}
+
+// gives an error naming the first field that is missing
+func requireFields(fields map[string]bool) error {
+	for _, name := range []string{"ac", "u", "created_at", "cm", "g", "n"} {
+		if ok, want := fields[name]; want && !ok {
+			return fmt.Errorf("showroom.parseEvent: missing %q", name)
+		}
+	}
+	return nil
 }
 
 func parseEvent(ev []byte) (object interface{}, err error) {
@@ -81,86 +114,85 @@ func parseEvent(ev []byte) (object interface{}, err error) {
 		return
 	}
 
-	parts := bytes.Split(ev, []byte("\t"))
-	bcsvrKey := string(parts[1])
-
-	// look for start of json and unmarshal it
-	ndx := bytes.Index(ev, []byte("{"))
-	if ndx == -1 {
+	parts := bytes.SplitN(ev, []byte("\t"), 3)
+	if len(parts) < 3 {
+		err = fmt.Errorf("showroom.parseEvent: short message: %q", ev)
 		return
 	}
-	j := ev[ndx:]
-	var data map[string]interface{}
-	if err = json.Unmarshal(j, &data); err != nil {
+	bcsvrKey := string(parts[1])
+
+	var data eventData
+	if err = json.Unmarshal(parts[2], &data); err != nil {
 		err = fmt.Errorf("showroom.parseEvent: %s", err)
 		return
 	}
 
-	switch data["t"] {
-	case "1":
+	user := func() User {
+		return User{Name: *data.Account, ID: int(data.UserID.n)}
+	}
+	event := func() Event {
+		return Event{bcsvrKey: bcsvrKey, At: time.Unix(data.CreatedAt.n, 0)}
+	}
+
+	switch data.Type.n {
+	case 1:
 		// comment
+		if err = requireFields(map[string]bool{
+			"ac":         data.Account != nil,
+			"u":          data.UserID.ok,
+			"created_at": data.CreatedAt.ok,
+			"cm":         data.Comment != nil,
+		}); err != nil {
+			return
+		}
 		return Comment{
-			User: User{
-				Name: data["ac"].(string),
-				ID:   int(data["u"].(float64)),
-			},
-			Event: Event{
-				bcsvrKey: bcsvrKey,
-				At:       time.Unix(int64(data["created_at"].(float64)), 0),
-			},
-			Text: data["cm"].(string),
+			User:  user(),
+			Event: event(),
+			Text:  *data.Comment,
 		}, nil
-	case "2":
+	case 2:
 		// gift
+		if err = requireFields(map[string]bool{
+			"ac":         data.Account != nil,
+			"u":          data.UserID.ok,
+			"created_at": data.CreatedAt.ok,
+			"g":          data.GiftID.ok,
+			"n":          data.Amount.ok,
+		}); err != nil {
+			return
+		}
 		return Gift{
-			User: User{
-				Name: data["ac"].(string),
-				ID:   int(data["u"].(float64)),
-			},
+			User:   user(),
+			Event:  event(),
+			ID:     int(data.GiftID.n),
+			Amount: int(data.Amount.n),
+		}, nil
+	case 8:
+		// telop change
+		// expecting = MSG	59eb86:iTW7lR5i	{"telop": "...", "t": 8, "api": "..."}
+		at := time.Now()
+		if data.CreatedAt.ok {
+			at = time.Unix(data.CreatedAt.n, 0)
+		}
+		return Telop{
 			Event: Event{
 				bcsvrKey: bcsvrKey,
-				At:       time.Unix(int64(data["created_at"].(float64)), 0),
+				At:       at,
 			},
-			ID:     int(data["u"].(float64)),
-			Amount: int(data["n"].(float64)),
+			Text: data.Telop,
 		}, nil
-	case 8:
-		// telop change
-		return
-	case 6:
-		// ?
-		return
 	default:
 		return
 	}
 }
 
-// RecordEvent stores the event in the database
-func RecordEvent(logFile *os.File, ev []byte) {
-	o, err := parseEvent(ev)
-	if err != nil {
-		log.Printf("showroom.RecordEvent: parseEvent: %s", err)
-		return
-	}
-	switch v := o.(type) {
-	// could write comments to recorded/[name]/[date].log
-	// now use tail -F [date].log to follow all the chat
-	case Comment:
-		fmt.Printf("record comment: %v (%v)\n", v, logFile)
-	case Gift:
-		fmt.Printf("record gift: %v (%v)\n", v, logFile)
-	case nil:
-	default:
-	}
-}
-
 // WatchEvents tracks websockets events for a given key
 // a separate websocket connection is made for each chat room
-func WatchEvents(ctx context.Context, bcsvrKey string) error {
-	url := url.URL{
-		Scheme: "wss",
-		Host:   bcsvrHost,
-	}
+// handle is called with each message until ctx is done
+// done is closed after the last message has been handled
+func WatchEvents(ctx context.Context, bcsvr broadcastServer, handle func([]byte)) (done <-chan struct{}, err error) {
+	url := bcsvr.url()
+	bcsvrKey := bcsvr.Key
 	ua := options.Get("user_agent")
 
 	// dial
@@ -176,7 +208,7 @@ func WatchEvents(ctx context.Context, bcsvrKey string) error {
 	log.Printf("showroom.connectChatServer: dial %s (%v)", url.String(), header)
 	c, _, err := dialer.DialContext(ctx, url.String(), header)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Printf("showroom.connectChatServer: connected")
 
@@ -185,30 +217,20 @@ func WatchEvents(ctx context.Context, bcsvrKey string) error {
 	log.Printf("showroom.connectChatServer: %s", subcmd)
 	if err := c.WriteMessage(websocket.TextMessage, subcmd); err != nil {
 		log.Printf("showroom.connectChatServer: tried to send: %s", err)
-		return err
-	}
-
-	// open chat log
-	logFile, err := os.OpenFile("chat.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Printf("showroom.SubscribeChat: failed to open log file: %s", err)
-		return err
+		c.Close()
+		return nil, err
 	}
 
-	// fmt.Fprintf(logFile, "# Started at %s", time.Now())
-
 	// commands
 	pingcmd := []byte("PING\tshowroom")
 	// quitcmd := []byte("QUIT")
 
-	msgSend = make(chan []byte, 1)
-	done := make(chan struct{}, 1)
+	readDone := make(chan struct{}, 1)
 	// read
 	track.Add(1)
 	go func() {
 		defer track.Done()
-		defer close(done)
-		defer logFile.Close()
+		defer close(readDone)
 		log.Printf("showroom.connectChatServer: read")
 		for {
 			_, msg, err := c.ReadMessage()
@@ -216,7 +238,7 @@ func WatchEvents(ctx context.Context, bcsvrKey string) error {
 				log.Println("showroom.connectChatServer: err:", err)
 				return
 			}
-			RecordEvent(logFile, msg)
+			handle(msg)
 		}
 	}()
 
@@ -232,7 +254,7 @@ func WatchEvents(ctx context.Context, bcsvrKey string) error {
 		defer pingTicker.Stop()
 		for {
 			select {
-			case <-done:
+			case <-readDone:
 				return
 			case <-pingTicker.C:
 				log.Printf("showroom.connectChatServer: %s", pingcmd)
@@ -256,7 +278,7 @@ func WatchEvents(ctx context.Context, bcsvrKey string) error {
 
 				// wait a bit for it to close
 				select {
-				case <-done:
+				case <-readDone:
 				case <-time.After(1 * time.Second):
 				}
 
@@ -266,5 +288,5 @@ func WatchEvents(ctx context.Context, bcsvrKey string) error {
 		}
 	}()
 
-	return nil
+	return readDone, nil
 }
@@ -0,0 +1,69 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEvent(t *testing.T) {
+	ev, err := parseEvent([]byte("MSG\t59eb86:iTW7lR5i\t{\"ac\":\"kyoko\",\"u\":1707048,\"created_at\":1547732326,\"cm\":\"hello\",\"t\":\"1\"}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := ev.(Comment)
+	if !ok || c.Name != "kyoko" || c.ID != 1707048 || c.Text != "hello" || !c.At.Equal(time.Unix(1547732326, 0)) {
+		t.Errorf("unexpected comment: %#v", ev)
+	}
+
+	ev, err = parseEvent([]byte("MSG\t59eb86:iTW7lR5i\t{\"ac\":\"kyoko\",\"u\":\"1707048\",\"created_at\":1547732326,\"g\":2,\"n\":10,\"t\":2}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, ok := ev.(Gift); !ok || g.ID != 2 || g.Amount != 10 || g.User.ID != 1707048 {
+		t.Errorf("unexpected gift: %#v", ev)
+	}
+
+	// anything else is ignored
+	if ev, err := parseEvent([]byte("MSG\t59eb86:iTW7lR5i\t{\"created_at\":1547732326,\"u\":1707048,\"at\":6,\"t\":6}")); ev != nil || err != nil {
+		t.Errorf("expected nothing: %#v %v", ev, err)
+	}
+	if ev, err := parseEvent([]byte("ACK\tshowroom")); ev != nil || err != nil {
+		t.Errorf("expected nothing: %#v %v", ev, err)
+	}
+
+	bad := []string{
+		// short
+		"MSG",
+		"MSG\t59eb86:iTW7lR5i",
+		"MSG\t59eb86:iTW7lR5i\t{\"ac\":",
+		// missing fields
+		"MSG\t59eb86:iTW7lR5i\t{\"u\":1707048,\"created_at\":1547732326,\"cm\":\"hello\",\"t\":1}",
+		"MSG\t59eb86:iTW7lR5i\t{\"ac\":\"kyoko\",\"u\":1707048,\"created_at\":1547732326,\"t\":1}",
+		"MSG\t59eb86:iTW7lR5i\t{\"ac\":\"kyoko\",\"u\":1707048,\"created_at\":1547732326,\"g\":2,\"t\":2}",
+		// wrong types
+		"MSG\t59eb86:iTW7lR5i\t{\"ac\":5,\"u\":1707048,\"created_at\":1547732326,\"cm\":\"hello\",\"t\":1}",
+		"MSG\t59eb86:iTW7lR5i\t{\"ac\":\"kyoko\",\"u\":\"someone\",\"created_at\":1547732326,\"cm\":\"hello\",\"t\":1}",
+		"MSG\t59eb86:iTW7lR5i\t{\"ac\":\"kyoko\",\"u\":1707048,\"created_at\":{},\"g\":2,\"n\":10,\"t\":2}",
+		"MSG\t59eb86:iTW7lR5i\t[1, 2]",
+	}
+	for _, msg := range bad {
+		if ev, err := parseEvent([]byte(msg)); err == nil {
+			t.Errorf("expected an error for %q: %#v", msg, ev)
+		}
+	}
+}
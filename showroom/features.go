@@ -1,5 +0,0 @@
-package showroom
-
-const (
-	ShouldWatchEvents = false
-)
@@ -22,11 +22,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/bobbytrapz/autosr/limit"
 	"github.com/bobbytrapz/autosr/options"
 	"github.com/bobbytrapz/autosr/retry"
 	"golang.org/x/net/html"
@@ -48,8 +51,9 @@ func init() {
 		panic(err)
 	}
 	httpClient = http.Client{
-		Jar:     httpCookieJar,
-		Timeout: 60 * time.Second,
+		Jar:       httpCookieJar,
+		Timeout:   60 * time.Second,
+		Transport: &limit.Transport{},
 	}
 }
 
@@ -82,21 +86,23 @@ func makeRequest(ctx context.Context, method, url string, body io.Reader, refere
 	return
 }
 
+// gives the url for a path on SHOWROOM
+// 'showroom_api' can point us at another server such as one used for testing
+func apiURL(format string, a ...interface{}) string {
+	base := strings.TrimSuffix(options.Get("showroom_api"), "/")
+	return base + fmt.Sprintf(format, a...)
+}
+
 func onlivesAPI() string {
-	return fmt.Sprintf("https://www.showroom-live.com/api/live/onlives?_=%d", time.Now().Unix())
+	return apiURL("/api/live/onlives?_=%d", time.Now().Unix())
 }
 
 func makeOnLivesRequest(ctx context.Context) (req *http.Request, err error) {
-	url := fmt.Sprintf("https://www.showroom-live.com/api/live/onlives?_=%d", time.Now().Unix())
-	req, err = makeRequest(ctx, "GET", url, nil, onlivesURL)
+	req, err = makeRequest(ctx, "GET", onlivesAPI(), nil, onlivesURL)
 	if err != nil {
 		return
 	}
 
-	// note: this cookie means we only get results for Idols genre
-	c := &http.Cookie{Name: "genreTabOnLive", Value: "102"}
-	req.AddCookie(c)
-
 	// xhr headers
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("X-Requested-With", "XMLHttpRequest")
@@ -118,15 +124,23 @@ func makeJSONRequest(ctx context.Context, endpoint string, id int) (req *http.Re
 }
 
 func makeIsLiveRequest(ctx context.Context, id int) (req *http.Request, err error) {
-	return makeJSONRequest(ctx, "https://www.showroom-live.com/room/is_live", id)
+	return makeJSONRequest(ctx, apiURL("/room/is_live"), id)
 }
 
 func makeStreamingURLRequest(ctx context.Context, id int) (req *http.Request, err error) {
-	return makeJSONRequest(ctx, "https://www.showroom-live.com/api/live/streaming_url", id)
+	return makeJSONRequest(ctx, apiURL("/api/live/streaming_url"), id)
+}
+
+func makeLiveInfoRequest(ctx context.Context, id int) (req *http.Request, err error) {
+	return makeJSONRequest(ctx, apiURL("/api/live/live_info"), id)
 }
 
 func makeNextLiveRequest(ctx context.Context, id int) (req *http.Request, err error) {
-	return makeJSONRequest(ctx, "https://www.showroom-live.com/api/room/next_live", id)
+	return makeJSONRequest(ctx, apiURL("/api/room/next_live"), id)
+}
+
+func makeTelopRequest(ctx context.Context, id int) (req *http.Request, err error) {
+	return makeJSONRequest(ctx, apiURL("/api/live/telop"), id)
 }
 
 // tells us if a certain showroom user is online
@@ -138,38 +152,20 @@ func checkIsLive(ctx context.Context, id int) (isLive bool, err error) {
 
 	res, err := httpClient.Do(req)
 	if err != nil {
-		err = retry.BoolError{
-			Message: fmt.Sprintf("showroom.checkIsLive: %s", err),
-			Attempt: func() (bool, error) {
-				return checkIsLive(ctx, id)
-			},
-		}
-
+		err = retry.Temporary(fmt.Errorf("showroom.checkIsLive: %w", err))
 		return
 	}
 	defer res.Body.Close()
 
 	buf, err := readResponse(res)
 	if err != nil {
-		err = retry.BoolError{
-			Message: fmt.Sprintf("showroom.checkIsLive: %s", err),
-			Attempt: func() (bool, error) {
-				return checkIsLive(ctx, id)
-			},
-		}
-
+		err = retry.Temporary(fmt.Errorf("showroom.checkIsLive: %w", err))
 		return
 	}
 
 	var data isLiveResponse
 	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
-		err = retry.BoolError{
-			Message: fmt.Sprintf("showroom.checkIsLive: %s", err),
-			Attempt: func() (bool, error) {
-				return checkIsLive(ctx, id)
-			},
-		}
-
+		err = retry.Temporary(fmt.Errorf("showroom.checkIsLive: %w", err))
 		return
 	}
 
@@ -178,7 +174,8 @@ func checkIsLive(ctx context.Context, id int) (isLive bool, err error) {
 	return
 }
 
-func checkStreamURL(ctx context.Context, id int) (streamURL string, err error) {
+// gives the stream that best matches pref. see selectStream.
+func checkStreamURL(ctx context.Context, id int, pref string) (chosen stream, err error) {
 	req, err := makeStreamingURLRequest(ctx, id)
 	if err != nil {
 		return
@@ -186,53 +183,24 @@ func checkStreamURL(ctx context.Context, id int) (streamURL string, err error) {
 
 	res, err := httpClient.Do(req)
 	if err != nil {
-		err = retry.StringError{
-			Message: fmt.Sprintf("showroom.checkStreamURL: %s", err),
-			Attempt: func() (string, error) {
-				return checkStreamURL(ctx, id)
-			},
-		}
-
+		err = retry.Temporary(fmt.Errorf("showroom.checkStreamURL: %w", err))
 		return
 	}
 	defer res.Body.Close()
 
 	buf, err := readResponse(res)
 	if err != nil {
-		err = retry.StringError{
-			Message: fmt.Sprintf("showroom.checkStreamURL: %s", err),
-			Attempt: func() (string, error) {
-				return checkStreamURL(ctx, id)
-			},
-		}
-
+		err = retry.Temporary(fmt.Errorf("showroom.checkStreamURL: %w", err))
 		return
 	}
 
 	var data streamingURLResponse
 	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
-		err = retry.StringError{
-			Message: fmt.Sprintf("showroom.checkStreamURL: %s", err),
-			Attempt: func() (string, error) {
-				return checkStreamURL(ctx, id)
-			},
-		}
-
+		err = retry.Temporary(fmt.Errorf("showroom.checkStreamURL: %w", err))
 		return
 	}
 
-	// find highest quality hls stream
-	var stream stream
-	for _, s := range data.StreamingURLs {
-		if s.Type != "hls" {
-			continue
-		}
-
-		if s.Quality > stream.Quality {
-			stream = s
-		}
-	}
-	streamURL = stream.URL
+	chosen, _ = selectStream(data.StreamingURLs, pref)
 
 	return
 }
@@ -245,50 +213,105 @@ func checkNextLive(ctx context.Context, id int) (at time.Time, err error) {
 
 	res, err := httpClient.Do(req)
 	if err != nil {
-		err = retry.TimeError{
-			Message: fmt.Sprintf("showroom.checkNextLive: %s", err),
-			Attempt: func() (time.Time, error) {
-				return checkNextLive(ctx, id)
-			},
-		}
-
+		err = retry.Temporary(fmt.Errorf("showroom.checkNextLive: %w", err))
 		return
 	}
 	defer res.Body.Close()
 
 	buf, err := readResponse(res)
 	if err != nil {
-		err = retry.TimeError{
-			Message: fmt.Sprintf("showroom.checkNextLive: %s", err),
-			Attempt: func() (time.Time, error) {
-				return checkNextLive(ctx, id)
-			},
-		}
-
+		err = retry.Temporary(fmt.Errorf("showroom.checkNextLive: %w", err))
 		return
 	}
 
 	var data nextLiveResponse
 	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
-		err = retry.TimeError{
-			Message: fmt.Sprintf("showroom.checkNextLive: %s", err),
-			Attempt: func() (time.Time, error) {
-				return checkNextLive(ctx, id)
-			},
-		}
+		err = retry.Temporary(fmt.Errorf("showroom.checkNextLive: %w", err))
+		return
+	}
+
+	// some rooms only announce in text
+	at, err = nextLiveAt(data.Epoch, data.Text, time.Now())
+	if err != nil {
+		log.Println("showroom.checkNextLive:", err)
+		return time.Time{}, nil
+	}
+
+	return
+}
+
+// gives the broadcast server for a room that is live
+func checkBroadcastServer(ctx context.Context, id int) (bcsvr broadcastServer, err error) {
+	req, err := makeLiveInfoRequest(ctx, id)
+	if err != nil {
+		return
+	}
 
+	res, err := httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("showroom.checkBroadcastServer: %s", err)
+		return
+	}
+	defer res.Body.Close()
+
+	buf, err := readResponse(res)
+	if err != nil {
+		err = fmt.Errorf("showroom.checkBroadcastServer: %s", err)
+		return
+	}
+
+	var data liveInfoResponse
+	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
+		err = fmt.Errorf("showroom.checkBroadcastServer: %s", err)
+		return
+	}
+
+	if data.BcsvrKey == "" {
+		err = fmt.Errorf("showroom.checkBroadcastServer: room %d has no broadcast key", id)
 		return
 	}
 
-	if data.Epoch > 0 {
-		at = time.Unix(data.Epoch, 0).Local()
+	bcsvr = broadcastServer{
+		Host: data.BcsvrHost,
+		Port: data.BcsvrPort,
+		Key:  data.BcsvrKey,
 	}
 
 	return
 }
 
-// fetch all showrooms
-func fetchAllRooms(ctx context.Context) (rooms []room, err error) {
+// gives the text shown over the stream of a room that is live
+func checkTelop(ctx context.Context, id int) (telop string, err error) {
+	req, err := makeTelopRequest(ctx, id)
+	if err != nil {
+		return
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("showroom.checkTelop: %s", err)
+		return
+	}
+	defer res.Body.Close()
+
+	buf, err := readResponse(res)
+	if err != nil {
+		err = fmt.Errorf("showroom.checkTelop: %s", err)
+		return
+	}
+
+	var data telopResponse
+	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
+		err = fmt.Errorf("showroom.checkTelop: %s", err)
+		return
+	}
+
+	return data.Telop, nil
+}
+
+// fetch every live room in every genre
+// a room may be listed in more than one genre
+func fetchAllRooms(ctx context.Context) (data onlivesResponse, err error) {
 	req, err := makeOnLivesRequest(ctx)
 	if err != nil {
 		err = fmt.Errorf("showroom.fetchRooms: %s", err)
@@ -297,6 +320,7 @@ func fetchAllRooms(ctx context.Context) (rooms []room, err error) {
 
 	res, err := httpClient.Do(req)
 	if err != nil {
+		err = fmt.Errorf("showroom.fetchRooms: %s", err)
 		return
 	}
 	defer res.Body.Close()
@@ -307,17 +331,11 @@ func fetchAllRooms(ctx context.Context) (rooms []room, err error) {
 		return
 	}
 
-	var data onlivesResponse
 	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
 		err = fmt.Errorf("showroom.fetchRooms: %s", err)
 		return
 	}
 
-	// note: this works because a cookie is set for Idol genre only
-	if len(data.Onlives) > 0 {
-		rooms = data.Onlives[0].Rooms
-	}
-
 	return
 }
 
@@ -346,9 +364,9 @@ func fetchPage(ctx context.Context, link string) (*html.Node, error) {
 	return doc, err
 }
 
-// information about a user's room is parsed from their page
+// information about a user's room
 func fetchRoom(ctx context.Context, link string) (roomStatus, error) {
-	return scrapeRoomData(ctx, link)
+	return lookupRoom(ctx, link)
 }
 
 // CommentURLFromRoomID given an id gives the url comments can be fetched from
@@ -383,6 +401,12 @@ func readResponse(res *http.Response) (buf *bytes.Buffer, err error) {
 
 	if res.StatusCode != 200 {
 		err = fmt.Errorf("showroom.readReponse: %s", res.Status)
+		// we are asked to slow down
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+			if d := limit.RetryAfter(res.Header.Get("Retry-After"), time.Now()); d > 0 {
+				err = retry.After(err, d)
+			}
+		}
 		return
 	}
 
@@ -0,0 +1,117 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// a page of the follow/rooms api
+type followsResponse struct {
+	Rooms []struct {
+		ID     json.Number `json:"room_id"`
+		Name   string      `json:"room_name"`
+		URLKey string      `json:"room_url_key"`
+	} `json:"rooms"`
+	NextPage int `json:"next_page"`
+}
+
+// FollowedRoom is a room the user follows on SHOWROOM
+type FollowedRoom struct {
+	ID     int
+	Name   string
+	URLKey string
+}
+
+// Link to the room
+func (r FollowedRoom) Link() string {
+	return roomLink(r.URLKey)
+}
+
+// do not follow a broken listing forever
+const maxFollowPages = 100
+
+// FetchFollows gives every room followed by the account we are logged in as
+// see showroom_cookies and showroom-login.json
+func FetchFollows(ctx context.Context) (rooms []FollowedRoom, err error) {
+	ensureSession(ctx)
+
+	for page := 1; page > 0 && page <= maxFollowPages; {
+		var data followsResponse
+		data, err = fetchFollowsPage(ctx, page)
+		if err != nil {
+			return
+		}
+
+		for _, r := range data.Rooms {
+			id, _ := strconv.Atoi(r.ID.String())
+			if r.URLKey == "" {
+				continue
+			}
+			rooms = append(rooms, FollowedRoom{
+				ID:     id,
+				Name:   r.Name,
+				URLKey: r.URLKey,
+			})
+			if id > 0 {
+				cacheRoom(r.URLKey, cachedRoom{ID: id, Name: r.Name})
+			}
+		}
+
+		if len(data.Rooms) == 0 || data.NextPage <= page {
+			break
+		}
+		page = data.NextPage
+	}
+
+	if len(rooms) == 0 {
+		err = fmt.Errorf("showroom.FetchFollows: no followed rooms found (are you logged in?)")
+	}
+
+	return
+}
+
+func fetchFollowsPage(ctx context.Context, page int) (data followsResponse, err error) {
+	link := apiURL("/api/follow/rooms?page=%d", page)
+	req, err := makeRequest(ctx, "GET", link, nil, "https://www.showroom-live.com/follow")
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("showroom.fetchFollowsPage: %s", err)
+		return
+	}
+	defer res.Body.Close()
+
+	buf, err := readResponse(res)
+	if err != nil {
+		err = fmt.Errorf("showroom.fetchFollowsPage: %s", err)
+		return
+	}
+
+	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
+		err = fmt.Errorf("showroom.fetchFollowsPage: %s", err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,476 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobbytrapz/autosr/limit"
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/retry"
+	"github.com/bobbytrapz/autosr/showroom/showroomtest"
+	"github.com/bobbytrapz/autosr/stats"
+	"github.com/bobbytrapz/autosr/track"
+	"github.com/bobbytrapz/autosr/track/tracktest"
+)
+
+type fakeTime struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (f *fakeTime) Now() time.Time {
+	f.Lock()
+	defer f.Unlock()
+	return f.now
+}
+
+func (f *fakeTime) Advance(d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// points the module at a pretend SHOWROOM
+func newFakeShowroom(t *testing.T) (*showroomtest.Server, *fakeTime) {
+	clock := &fakeTime{now: time.Now()}
+	srv := showroomtest.NewServer()
+	srv.Now = clock.Now
+
+	api := options.Get("showroom_api")
+	options.Set("showroom_api", srv.URL)
+
+	cachePath := roomCachePath
+	roomCachePath = filepath.Join(t.TempDir(), "showroom-rooms.json")
+	roomCache.Lock()
+	roomCache.loaded = false
+	roomCache.rooms = make(map[string]cachedRoom)
+	roomCache.Unlock()
+
+	session.Lock()
+	session.triedLogin = true
+	session.Unlock()
+
+	forgetLiveRooms()
+
+	t.Cleanup(func() {
+		forgetLiveRooms()
+		roomCachePath = cachePath
+		options.Set("showroom_api", api)
+		srv.Close()
+	})
+
+	return srv, clock
+}
+
+// the next liveRooms asks the api again
+func forgetLiveRooms() {
+	onlives.Lock()
+	onlives.fetchedAt = time.Time{}
+	onlives.Unlock()
+}
+
+func get(t *testing.T, link string) []byte {
+	t.Helper()
+	res, err := http.Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("%s: %s", link, res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestFakeShowroomLive(t *testing.T) {
+	srv, clock := newFakeShowroom(t)
+	srv.AddRoom(1234, "akane", "Akane")
+	ctx := context.Background()
+
+	added, err := module.AddTarget(ctx, srv.Link(1234))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := added.(*target)
+	if tt.id != 1234 || tt.Name() != "Akane" {
+		t.Fatalf("unexpected target: %d %q", tt.id, tt.Name())
+	}
+	// an offline room has no broadcast server to ask about
+	tt.BeginSnipe(ctx)
+	if n := srv.Requests("/api/live/live_info"); n != 0 {
+		t.Errorf("expected no live info requests while offline: %d", n)
+	}
+
+	// offline with an announcement
+	if isLive, err := tt.CheckLive(ctx); isLive || err == nil {
+		t.Errorf("expected offline: %v %v", isLive, err)
+	}
+	next := time.Now().Add(time.Hour).Truncate(time.Second)
+	srv.SetNextLive(1234, next, "")
+	if at, err := checkNextLive(ctx, tt.id); err != nil || !at.Equal(next) {
+		t.Errorf("unexpected next live: %s %v", at, err)
+	}
+	textOnly := time.Now().Add(2 * time.Hour).In(jst).Truncate(time.Minute)
+	srv.SetNextLive(1234, time.Time{}, textOnly.Format("1/2 15:04"))
+	if at, err := checkNextLive(ctx, tt.id); err != nil || !at.Equal(textOnly) {
+		t.Errorf("unexpected text next live: %s %v", at, err)
+	}
+
+	// live
+	srv.GoLive(1234)
+	live, _, err := liveRooms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := live[1234]; !ok {
+		t.Error("expected the room to be listed as live")
+	}
+	if isLive, err := tt.CheckLive(ctx); !isLive || err != nil {
+		t.Errorf("expected live: %v %v", isLive, err)
+	}
+	streamURL, err := tt.CheckStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tt.chosenStream().Quality != 1000 {
+		t.Errorf("expected the best stream: %+v", tt.chosenStream())
+	}
+	if tt.broadcastServer().Key == "" {
+		t.Error("expected a broadcast server")
+	}
+
+	// a recorder can follow the playlist
+	clock.Advance(10 * showroomtest.SegmentDuration)
+	playlist := string(get(t, streamURL))
+	if !strings.HasPrefix(playlist, "#EXTM3U") || !strings.Contains(playlist, "\n10.ts\n") {
+		t.Errorf("unexpected playlist:\n%s", playlist)
+	}
+	segURL := streamURL[:strings.LastIndex(streamURL, "/")+1] + "10.ts"
+	if seg := get(t, segURL); !bytes.Equal(seg, showroomtest.Segment(10)) || seg[0] != 0x47 {
+		t.Error("unexpected segment")
+	}
+
+	// the live ends
+	srv.EndLive(1234)
+	if playlist := string(get(t, streamURL)); !strings.Contains(playlist, "#EXT-X-ENDLIST") {
+		t.Errorf("expected the playlist to end:\n%s", playlist)
+	}
+	if isLive, _ := tt.CheckLive(ctx); isLive {
+		t.Error("expected offline after the live ends")
+	}
+}
+
+func TestFakeShowroomTicket(t *testing.T) {
+	srv, _ := newFakeShowroom(t)
+	srv.AddRoom(42, "premium", "Premium").Premium = true
+	ctx := context.Background()
+
+	added, err := module.AddTarget(ctx, srv.Link(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := added.(*target)
+	profiles := srv.Requests("/api/room/profile")
+
+	// we do not ask about a ticket while they are offline
+	for i := 0; i < 3; i++ {
+		if _, err := tt.CheckStream(ctx); err == nil || errors.Is(err, track.ErrTicketRequired) {
+			t.Errorf("expected no stream: %v", err)
+		}
+	}
+	if n := srv.Requests("/api/room/profile") - profiles; n != 0 {
+		t.Errorf("expected no profile requests while offline: %d", n)
+	}
+
+	// we ask once for each live
+	srv.GoLive(42)
+	forgetLiveRooms()
+	for i := 0; i < 3; i++ {
+		if _, err := tt.CheckStream(ctx); !errors.Is(err, track.ErrTicketRequired) {
+			t.Errorf("expected a ticket to be required: %v", err)
+		}
+	}
+	if n := srv.Requests("/api/room/profile") - profiles; n != 1 {
+		t.Errorf("expected one profile request for the live: %d", n)
+	}
+
+	srv.EndLive(42)
+	srv.GoLive(42)
+	forgetLiveRooms()
+	if _, err := tt.CheckStream(ctx); !errors.Is(err, track.ErrTicketRequired) {
+		t.Errorf("expected a ticket to be required: %v", err)
+	}
+	if n := srv.Requests("/api/room/profile") - profiles; n != 2 {
+		t.Errorf("expected one more profile request for the new live: %d", n)
+	}
+}
+
+func TestFakeShowroomRetries(t *testing.T) {
+	srv, _ := newFakeShowroom(t)
+	srv.AddRoom(7, "nana", "Nana")
+	ctx := context.Background()
+
+	added, err := module.AddTarget(ctx, srv.Link(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := added.(*target)
+
+	// no stream yet is left for the next poll
+	streams := srv.Requests("/api/live/streaming_url")
+	if _, err := retryFetch(ctx, tt.CheckStream); !errors.Is(err, errNoStream) {
+		t.Errorf("expected no stream: %v", err)
+	}
+	if n := srv.Requests("/api/live/streaming_url") - streams; n != 1 {
+		t.Errorf("expected one streaming url request: %d", n)
+	}
+
+	// a failure is asked again
+	srv.GoLive(7)
+	srv.Fail("/api/live/streaming_url", 1, http.StatusServiceUnavailable)
+	streams = srv.Requests("/api/live/streaming_url")
+	if streamURL, err := retryFetch(ctx, tt.CheckStream); err != nil || streamURL == "" {
+		t.Errorf("expected a stream after the failure: %q %v", streamURL, err)
+	}
+	if n := srv.Requests("/api/live/streaming_url") - streams; n != 2 {
+		t.Errorf("expected two streaming url requests: %d", n)
+	}
+}
+
+func TestFakeShowroomErrors(t *testing.T) {
+	srv, _ := newFakeShowroom(t)
+	srv.AddRoom(99, "kyoko", "Kyoko")
+	srv.GoLive(99)
+	srv.SetGzip(true)
+	ctx := context.Background()
+
+	if isLive, err := checkIsLive(ctx, 99); !isLive || err != nil {
+		t.Fatalf("expected gzip to be read: %v %v", isLive, err)
+	}
+
+	// a failure can be retried
+	srv.Fail("/room/is_live", 1, http.StatusServiceUnavailable)
+	_, err := checkIsLive(ctx, 99)
+	if !retry.IsTemporary(err) {
+		t.Fatalf("expected a retryable error: %v", err)
+	}
+	if isLive, err := checkIsLive(ctx, 99); !isLive || err != nil {
+		t.Errorf("expected the retry to work: %v %v", isLive, err)
+	}
+
+	// we wait as long as we are asked to
+	srv.Throttle("/room/is_live", 1, 2*time.Second)
+	_, err = checkIsLive(ctx, 99)
+	if !retry.IsTemporary(err) || retry.Delay(err) != 2*time.Second {
+		t.Errorf("expected to be asked to wait 2s: %v %v", retry.Delay(err), err)
+	}
+
+	// a stream check stops asking when it is told to slow down
+	tt := &target{name: "Kyoko", id: 99, urlKey: "kyoko", link: srv.Link(99)}
+	profiles, nextLives := srv.Requests("/api/room/profile"), srv.Requests("/api/room/next_live")
+	srv.Throttle("/api/live/streaming_url", 1, 3*time.Second)
+	_, err = tt.CheckStream(ctx)
+	if !retry.IsTemporary(err) || retry.Delay(err) != 3*time.Second {
+		t.Errorf("expected to be asked to wait 3s: %v %v", retry.Delay(err), err)
+	}
+	if srv.Requests("/api/room/profile") != profiles || srv.Requests("/api/room/next_live") != nextLives {
+		t.Error("expected nothing else to be asked after being told to slow down")
+	}
+
+	// a room we found before is not asked about again
+	if _, err := lookupRoom(ctx, srv.Link(99)); err != nil {
+		t.Fatal(err)
+	}
+	status, err := lookupRoom(ctx, srv.Link(99))
+	if err != nil || status.ID != 99 || status.Name != "Kyoko" {
+		t.Errorf("expected the cached room: %+v %v", status, err)
+	}
+	if n := srv.Requests("/api/room/status"); n != 1 {
+		t.Errorf("expected 1 status request: %d", n)
+	}
+
+	// an old one is but it is still found when the api fails
+	maxAge := roomCacheMaxAge
+	roomCacheMaxAge = 0
+	defer func() {
+		roomCacheMaxAge = maxAge
+	}()
+	srv.Fail("/api/room/status", 1, http.StatusInternalServerError)
+	status, err = lookupRoom(ctx, srv.Link(99))
+	if err != nil || status.ID != 99 {
+		t.Errorf("expected the cached room: %+v %v", status, err)
+	}
+	if n := srv.Requests("/api/room/status"); n != 2 {
+		t.Errorf("expected 2 status requests: %d", n)
+	}
+}
+
+func TestFakeShowroomOutage(t *testing.T) {
+	srv, _ := newFakeShowroom(t)
+	srv.AddRoom(99, "kyoko", "Kyoko")
+	srv.GoLive(99)
+	ctx := context.Background()
+
+	// every target gives up on a site that keeps failing
+	srv.Fail("/room/is_live", 100, http.StatusServiceUnavailable)
+	for i := 0; i < 10; i++ {
+		checkIsLive(ctx, 99)
+	}
+	_, err := checkIsLive(ctx, 99)
+	if !errors.Is(err, limit.ErrOpen) || retry.Delay(err) <= 0 {
+		t.Errorf("expected to stop asking: %v", err)
+	}
+	if n := srv.Requests("/room/is_live"); n != 5 {
+		t.Errorf("expected 5 requests before we stopped: %d", n)
+	}
+}
+
+func TestFakeShowroomKeepsCase(t *testing.T) {
+	srv, _ := newFakeShowroom(t)
+	srv.AddRoom(5, "MixedCase", "Mixed")
+	ctx := context.Background()
+
+	// the second time the room is found in our cache
+	for i := 0; i < 2; i++ {
+		link, err := module.NormalizeLink(ctx, "5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link != "https://www.showroom-live.com/MixedCase" {
+			t.Errorf("expected the case the site gave us: %q", link)
+		}
+	}
+	if n := srv.Requests("/api/room/profile"); n != 1 {
+		t.Errorf("expected one profile request: %d", n)
+	}
+}
+
+func TestFakeShowroomTracked(t *testing.T) {
+	srv, _ := newFakeShowroom(t)
+	srv.AddRoom(99, "yui", "Yui")
+
+	clock := tracktest.NewClock(time.Now())
+	srv.Now = clock.Now
+	runner := tracktest.NewRunner()
+
+	configPath := options.ConfigPath
+	options.ConfigPath = t.TempDir()
+	saveTo := options.Get("save_to")
+	options.Set("save_to", t.TempDir())
+	adaptive := options.GetBool("adaptive_polling")
+	options.Set("adaptive_polling", false)
+	// poll asks for the live rooms each time it checks
+	maxAge := onlivesMaxAge
+	onlivesMaxAge = 0
+	restoreClock := track.SetClock(clock)
+	restoreRunner := track.SetRunner(runner)
+	t.Cleanup(func() {
+		restoreRunner()
+		restoreClock()
+		onlivesMaxAge = maxAge
+		options.Set("adaptive_polling", adaptive)
+		options.Set("save_to", saveTo)
+		options.ConfigPath = configPath
+	})
+
+	// everything we started must finish before we put things back
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		track.Wait()
+	})
+
+	// moves the clock until done gives true
+	advanceUntil := func(done func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !done() {
+			if time.Now().After(deadline) {
+				t.Fatal("gave up waiting at", clock.Now())
+			}
+			clock.Advance(time.Second)
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	link := srv.Link(99)
+	if err := track.AppendList("fake showroom", link); err != nil {
+		t.Fatal(err)
+	}
+	if err := track.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := track.RemoveTarget(ctx, link); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// everyone is checked when poll begins
+	advanceUntil(func() bool {
+		return srv.Requests("/api/live/onlives") > 0
+	})
+	if len(runner.Started()) > 0 {
+		t.Fatal("expected no save while they are offline")
+	}
+
+	// poll finds them once they go live and snipes them
+	srv.GoLive(99)
+	var p *tracktest.Process
+	advanceUntil(func() bool {
+		select {
+		case p = <-runner.Started():
+			return true
+		default:
+			return false
+		}
+	})
+	if !strings.Contains(strings.Join(p.Args, " "), "/hls/99/") {
+		t.Errorf("expected the room's stream: %v", p.Args)
+	}
+	live := track.Display().Live
+	if len(live) != 1 || live[0].Link != link {
+		t.Errorf("expected them to be shown as live: %+v", live)
+	}
+
+	// the live ends and the save is recorded
+	srv.EndLive(99)
+	p.Exit(nil)
+	advanceUntil(func() bool {
+		return len(track.SavingPaths()) == 0
+	})
+	sessions, err := stats.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Link != link {
+		t.Errorf("expected the save to be recorded: %+v", sessions)
+	}
+}
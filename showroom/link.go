@@ -0,0 +1,128 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// true for showroom-live.com and any subdomain such as the mobile site
+func isShowroomHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "showroom-live.com" || strings.HasSuffix(host, ".showroom-live.com")
+}
+
+// room url keys are not case sensitive so /ROOM and /room are the same room
+// links keep the case they were given and this is only used to compare them
+func roomKey(urlKey string) string {
+	return strings.ToLower(urlKey)
+}
+
+// canonical link for a room
+func roomLink(urlKey string) string {
+	u := url.URL{
+		Scheme: "https",
+		Host:   domainName,
+		Path:   urlKey,
+	}
+	return u.String()
+}
+
+// parseRoomLink finds the room a link is for
+// gives either the url key or the room id
+// accepts
+//
+//	https://www.showroom-live.com/ROOM
+//	https://www.showroom-live.com/r/ROOM
+//	https://www.showroom-live.com/room/profile?room_id=12345
+//	https://showroom-live.com/ROOM and other subdomains such as the mobile site
+//	12345
+func parseRoomLink(link string) (urlKey string, id int, err error) {
+	link = strings.TrimSpace(link)
+
+	// a bare room id
+	if n, err := strconv.Atoi(link); err == nil && n > 0 {
+		return "", n, nil
+	}
+
+	if !strings.Contains(link, "://") {
+		// www.showroom-live.com/ROOM
+		link = "https://" + link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", 0, err
+	}
+	if !isShowroomHost(u.Hostname()) {
+		return "", 0, fmt.Errorf("showroom.parseRoomLink: not a showroom link: %q", link)
+	}
+
+	// room_id is given on profile pages and some app links
+	if s := u.Query().Get("room_id"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return "", 0, fmt.Errorf("showroom.parseRoomLink: invalid room id: %q", link)
+		}
+		return "", n, nil
+	}
+
+	var parts []string
+	for _, p := range strings.Split(u.Path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+
+	switch {
+	case len(parts) == 1:
+		urlKey = parts[0]
+	case len(parts) == 2 && parts[0] == "room" && parts[1] == "profile":
+		return "", 0, fmt.Errorf("showroom.parseRoomLink: profile link without a room id: %q", link)
+	case len(parts) == 2 && (parts[0] == "r" || parts[0] == "room"):
+		urlKey = parts[1]
+	default:
+		return "", 0, fmt.Errorf("showroom.parseRoomLink: not a link to a room: %q", link)
+	}
+
+	return urlKey, 0, nil
+}
+
+// CaseInsensitive since /ROOM and /room are the same room
+func (m Module) CaseInsensitive() bool {
+	return true
+}
+
+// NormalizeLink gives the canonical link for a room
+func (m Module) NormalizeLink(ctx context.Context, link string) (string, error) {
+	urlKey, id, err := parseRoomLink(link)
+	if err != nil {
+		return "", err
+	}
+
+	if urlKey == "" {
+		urlKey, err = urlKeyForID(ctx, id)
+		if err != nil {
+			return "", fmt.Errorf("showroom.NormalizeLink: %s", err)
+		}
+	}
+
+	return roomLink(urlKey), nil
+}
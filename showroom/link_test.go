@@ -0,0 +1,66 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"testing"
+)
+
+func TestParseRoomLink(t *testing.T) {
+	cases := []struct {
+		link   string
+		urlKey string
+		id     int
+		fails  bool
+	}{
+		{"https://www.showroom-live.com/ROOM", "ROOM", 0, false},
+		{"https://www.showroom-live.com/ROOM/", "ROOM", 0, false},
+		{"https://showroom-live.com/ROOM", "ROOM", 0, false},
+		{"https://m.showroom-live.com/r/ROOM", "ROOM", 0, false},
+		{"www.showroom-live.com/ROOM?t=1", "ROOM", 0, false},
+		{"https://www.showroom-live.com/room/profile?room_id=12345", "", 12345, false},
+		{"12345", "", 12345, false},
+		{" 12345 ", "", 12345, false},
+		{"https://www.showroom-live.com/", "", 0, true},
+		{"https://www.showroom-live.com/room/profile?room_id=abc", "", 0, true},
+		{"https://example.com/ROOM", "", 0, true},
+		{"https://www.showroom-live.com/room/profile", "", 0, true},
+	}
+
+	for _, c := range cases {
+		urlKey, id, err := parseRoomLink(c.link)
+		if c.fails {
+			if err == nil {
+				t.Errorf("parseRoomLink(%q) should fail", c.link)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRoomLink(%q): %s", c.link, err)
+			continue
+		}
+		if urlKey != c.urlKey || id != c.id {
+			t.Errorf("parseRoomLink(%q) = %q, %d; want %q, %d", c.link, urlKey, id, c.urlKey, c.id)
+		}
+	}
+}
+
+func TestRoomLinkKeepsCase(t *testing.T) {
+	// links we already track must not change
+	if link := roomLink("ROOM"); link != "https://www.showroom-live.com/ROOM" {
+		t.Errorf("unexpected link: %q", link)
+	}
+}
@@ -0,0 +1,278 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+// rooms we have found before so we do not have to ask again
+// and can still track them when the api fails
+var roomCachePath = filepath.Join(options.ConfigPath, "showroom-rooms.json")
+
+// we ask about a cached room again after this long in case it changed its name
+var roomCacheMaxAge = 7 * 24 * time.Hour
+
+type cachedRoom struct {
+	ID   int
+	Name string
+	// the case the site gave us since rooms are cached by their key in lower case
+	URLKey string `json:",omitempty"`
+	// when the site told us about the room
+	FoundAt time.Time `json:",omitempty"`
+}
+
+var roomCache = struct {
+	sync.Mutex
+	loaded bool
+	rooms  map[string]cachedRoom
+}{
+	rooms: make(map[string]cachedRoom),
+}
+
+// expects roomCache to be locked
+func loadRoomCache() {
+	if roomCache.loaded {
+		return
+	}
+	roomCache.loaded = true
+
+	data, err := ioutil.ReadFile(roomCachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Println("showroom.loadRoomCache:", err)
+		}
+		return
+	}
+
+	if err := json.Unmarshal(data, &roomCache.rooms); err != nil {
+		log.Println("showroom.loadRoomCache:", err)
+	}
+}
+
+func cachedRoomFor(urlKey string) (room cachedRoom, ok bool) {
+	roomCache.Lock()
+	defer roomCache.Unlock()
+	loadRoomCache()
+	room, ok = roomCache.rooms[roomKey(urlKey)]
+	return
+}
+
+// gives the url key of a cached room with the given id
+func cachedURLKeyFor(id int) (urlKey string, ok bool) {
+	roomCache.Lock()
+	defer roomCache.Unlock()
+	loadRoomCache()
+	for key, room := range roomCache.rooms {
+		if room.ID == id {
+			if room.URLKey != "" {
+				return room.URLKey, true
+			}
+			return key, true
+		}
+	}
+	return
+}
+
+func cacheRoom(urlKey string, room cachedRoom) {
+	roomCache.Lock()
+	defer roomCache.Unlock()
+	loadRoomCache()
+
+	key := roomKey(urlKey)
+	room.URLKey = urlKey
+	room.FoundAt = time.Now()
+	roomCache.rooms[key] = room
+
+	data, err := json.MarshalIndent(roomCache.rooms, "", "  ")
+	if err != nil {
+		log.Println("showroom.cacheRoom:", err)
+		return
+	}
+	if err := ioutil.WriteFile(roomCachePath, data, 0600); err != nil {
+		log.Println("showroom.cacheRoom:", err)
+	}
+}
+
+// gives the room url key from a link to a room
+func urlKeyFromLink(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	key := path.Base(strings.TrimSuffix(u.Path, "/"))
+	if key == "" || key == "." || key == "/" {
+		return "", fmt.Errorf("showroom.urlKeyFromLink: no room in link: %q", link)
+	}
+	return key, nil
+}
+
+func roomStatusAPI(urlKey string) string {
+	return apiURL("/api/room/status?room_url_key=%s", url.QueryEscape(urlKey))
+}
+
+func roomProfileAPI(id int) string {
+	return apiURL("/api/room/profile?room_id=%d", id)
+}
+
+// looks up a room by id with the api
+func checkRoomProfile(ctx context.Context, id int) (data roomProfileResponse, err error) {
+	req, err := makeRequest(ctx, "GET", roomProfileAPI(id), nil, "")
+	if err != nil {
+		return
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("showroom.checkRoomProfile: %s", err)
+		return
+	}
+	defer res.Body.Close()
+
+	buf, err := readResponse(res)
+	if err != nil {
+		err = fmt.Errorf("showroom.checkRoomProfile: %s", err)
+		return
+	}
+
+	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
+		err = fmt.Errorf("showroom.checkRoomProfile: %s", err)
+		return
+	}
+
+	if data.URLKey == "" {
+		err = fmt.Errorf("showroom.checkRoomProfile: room not found: %d", id)
+		return
+	}
+
+	return
+}
+
+// gives the url key for a room id
+func urlKeyForID(ctx context.Context, id int) (string, error) {
+	if key, ok := cachedURLKeyFor(id); ok {
+		return key, nil
+	}
+
+	data, err := checkRoomProfile(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	cacheRoom(data.URLKey, cachedRoom{ID: id, Name: data.Name})
+
+	return data.URLKey, nil
+}
+
+// looks up a room with the api
+func checkRoomStatus(ctx context.Context, urlKey string) (data roomStatusResponse, err error) {
+	req, err := makeRequest(ctx, "GET", roomStatusAPI(urlKey), nil, "")
+	if err != nil {
+		return
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		err = fmt.Errorf("showroom.checkRoomStatus: %s", err)
+		return
+	}
+	defer res.Body.Close()
+
+	buf, err := readResponse(res)
+	if err != nil {
+		err = fmt.Errorf("showroom.checkRoomStatus: %s", err)
+		return
+	}
+
+	if err = json.Unmarshal(buf.Bytes(), &data); err != nil {
+		err = fmt.Errorf("showroom.checkRoomStatus: %s", err)
+		return
+	}
+
+	if data.ID == 0 {
+		err = fmt.Errorf("showroom.checkRoomStatus: room not found: %q", urlKey)
+		return
+	}
+
+	return
+}
+
+// information about a user's room
+// we use our cache unless it is old then ask the api
+// an old cached room is still used if the api fails
+// the browser is only used if the user asks for it
+func lookupRoom(ctx context.Context, link string) (status roomStatus, err error) {
+	urlKey, err := urlKeyFromLink(link)
+	if err != nil {
+		return
+	}
+
+	cached, isCached := cachedRoomFor(urlKey)
+	if isCached && time.Since(cached.FoundAt) < roomCacheMaxAge {
+		return cachedStatus(urlKey, cached), nil
+	}
+
+	data, err := checkRoomStatus(ctx, urlKey)
+	if err == nil {
+		status.ID = data.ID
+		status.Name = data.Name
+		status.Live = data.IsLive
+		status.StartedAt = data.StartedAt
+		status.LiveID = data.LiveID
+		status.LiveRoom.URLKey = data.URLKey
+		cacheRoom(data.URLKey, cachedRoom{ID: data.ID, Name: data.Name})
+		return
+	}
+	log.Println("showroom.lookupRoom:", err)
+
+	if isCached {
+		log.Println("showroom.lookupRoom: using cached room for", urlKey)
+		return cachedStatus(urlKey, cached), nil
+	}
+
+	if options.GetBool("showroom_use_browser") {
+		log.Println("showroom.lookupRoom: using browser for", urlKey)
+		status, err = scrapeRoomData(ctx, link)
+		if err == nil {
+			cacheRoom(urlKey, cachedRoom{ID: status.ID, Name: status.Name})
+		}
+		return
+	}
+
+	return
+}
+
+func cachedStatus(urlKey string, room cachedRoom) (status roomStatus) {
+	status.ID = room.ID
+	status.Name = room.Name
+	status.LiveRoom.URLKey = urlKey
+	if room.URLKey != "" {
+		status.LiveRoom.URLKey = room.URLKey
+	}
+	return
+}
@@ -18,6 +18,7 @@ package showroom
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -48,6 +49,12 @@ func (m Module) Hostname() string {
 	return "www.showroom-live.com"
 }
 
+// Hostnames gives every other hostname a room link may have
+// such as the mobile site
+func (m Module) Hostnames() []string {
+	return []string{"showroom-live.com", "*.showroom-live.com"}
+}
+
 func fetchTargetInformation(ctx context.Context, link string) (*target, error) {
 	// if there is a failure we return an incomplete target
 	t := &target{
@@ -63,8 +70,8 @@ func fetchTargetInformation(ctx context.Context, link string) (*target, error) {
 	t.id = s.ID
 	t.urlKey = s.LiveRoom.URLKey
 
-	// todo: we need to get the bcsvrKey
-	t.bcsvrKey = ""
+	// only a room that is live has a broadcast server
+	// so we find it when they are live instead of asking for every room
 
 	t.name = strings.TrimSpace(s.Name)
 	// note: this works around a display bug in gocui
@@ -86,6 +93,7 @@ func (m Module) AddTarget(ctx context.Context, link string) (track.Target, error
 	if err != nil {
 		return nil, fmt.Errorf("showroom.AddTarget: '%s' %s", link, err)
 	}
+	ensureSession(ctx)
 
 	added, err := fetchTargetInformation(ctx, link)
 	if err != nil {
@@ -97,14 +105,143 @@ func (m Module) AddTarget(ctx context.Context, link string) (track.Target, error
 	return added, nil
 }
 
+// how long we trust a listing of live rooms
+var onlivesMaxAge = 30 * time.Second
+
+// upcoming times do not change often so we check them less often than live status
+var nextLiveEvery = 30 * time.Minute
+
+var onlives = struct {
+	sync.Mutex
+	fetchedAt time.Time
+	rooms     map[int]room
+	host      string
+	port      int
+}{}
+
+// gives every live room by id
+// one request tells us about every room so we share it for a little while
+func liveRooms(ctx context.Context) (rooms map[int]room, bcsvr broadcastServer, err error) {
+	onlives.Lock()
+	defer onlives.Unlock()
+
+	if time.Since(onlives.fetchedAt) > onlivesMaxAge {
+		var data onlivesResponse
+		data, err = fetchAllRooms(ctx)
+		if err != nil {
+			return
+		}
+
+		onlives.rooms = make(map[int]room)
+		for _, genre := range data.Onlives {
+			for _, r := range genre.Rooms {
+				if r.GenreName == "" {
+					r.GenreName = genre.GenreName
+				}
+				onlives.rooms[r.ID] = r
+			}
+		}
+		onlives.host = data.Host
+		onlives.port = data.Port
+		onlives.fetchedAt = time.Now()
+		log.Println("showroom.liveRooms:", len(onlives.rooms), "rooms are live")
+	}
+
+	return onlives.rooms, broadcastServer{Host: onlives.host, Port: onlives.port}, nil
+}
+
 // CheckUpcoming streams and snipe them
+// we find who is live from the list of every live room
+// and only ask about the rooms of targets that are live
 func (m Module) CheckUpcoming(ctx context.Context, targets []track.Target) error {
 	if len(targets) == 0 {
 		log.Println("showroom.CheckUpcoming: no targets")
 		return nil
 	}
 	log.Println("showroom.CheckUpcoming:", len(targets), "targets")
+	// the user may have given us a new cookie file
+	ensureSession(ctx)
+
+	live, bcsvr, err := liveRooms(ctx)
+	if err != nil {
+		log.Println("showroom.CheckUpcoming: checking each room:", err)
+		return checkEachTarget(ctx, targets)
+	}
+
+	var each []track.Target
+	var waitCheck sync.WaitGroup
+	for _, tt := range targets {
+		t, ok := tt.(*target)
+		if !ok || t.id == 0 {
+			// we need to ask the room itself
+			each = append(each, tt)
+			continue
+		}
+
+		r, isLive := live[t.id]
+		if !isLive {
+			if t.shouldCheckNextLive() {
+				waitCheck.Add(1)
+				go func(t *target) {
+					defer waitCheck.Done()
+					_, err := retryFetch(ctx, func(ctx context.Context) (struct{}, error) {
+						return struct{}{}, t.checkNextLive(ctx)
+					})
+					if err != nil {
+						log.Println("showroom.CheckUpcoming:", err)
+					}
+				}(t)
+			}
+			continue
+		}
+
+		t.setLiveRoom(r, broadcastServer{Host: bcsvr.Host, Port: bcsvr.Port, Key: r.Key})
+		each = append(each, t)
+	}
+
+	if len(each) > 0 {
+		waitCheck.Add(1)
+		go func() {
+			defer waitCheck.Done()
+			checkEachTarget(ctx, each)
+		}()
+	}
+
+	// wait for each target to finish checking
+	done := make(chan struct{}, 1)
+	go func() {
+		defer close(done)
+		waitCheck.Wait()
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("showroom.CheckUpcoming:", ctx.Err())
+	}
 
+	return nil
+}
+
+// how long each room is asked before we wait for the next poll
+var fetchTimeout = 7 * time.Second
+
+// asks the site again when it fails for a moment
+// waiting for a stream is left to the next poll
+func retryFetch[T any](ctx context.Context, fetch func(context.Context) (T, error)) (T, error) {
+	timeout := time.NewTimer(fetchTimeout)
+	defer timeout.Stop()
+
+	return retry.Do(ctx, retry.Options{
+		Retryable: func(err error) bool {
+			return retry.IsTemporary(err) && !errors.Is(err, errNoStream)
+		},
+		Policy:  backoff.For(module.Hostname(), backoff.Upcoming),
+		Timeout: timeout.C,
+	}, fetch)
+}
+
+// asks each target's room for a stream or upcoming time
+func checkEachTarget(ctx context.Context, targets []track.Target) error {
 	rw.RLock()
 	defer rw.RUnlock()
 	var waitCheck sync.WaitGroup
@@ -114,47 +251,24 @@ func (m Module) CheckUpcoming(ctx context.Context, targets []track.Target) error
 			defer waitCheck.Done()
 			name := t.Name()
 
-			// each target gets a separate timeout
-			// check is called by poll so we only check for a little while
-			timeout := time.NewTimer(7 * time.Second)
-			defer timeout.Stop()
-
 			// check target's actual room for stream url or upcoming date
-			var err error
-			if _, err = t.CheckStream(ctx); err == nil {
+			// if there is no stream yet or there is a new time we wait for the next poll
+			_, err := retryFetch(ctx, t.CheckStream)
+			switch {
+			case err == nil:
 				log.Println("showroom.CheckUpcoming:", name, "is live now!")
 				// they are live now so snipe them now
 				if err = track.SnipeTargetAt(ctx, t, time.Now()); err != nil {
 					log.Println("showroom.CheckUpcoming:", err)
 				}
-				return
-			}
-
-			numAttempts := 0
-			e, ok := retry.StringCheck(err)
-			for ; ok; e, ok = retry.StringCheck(err) {
-				// check if error just a stream was not found or new time
-				// if so, we should not bother retrying
-				if !strings.HasPrefix(err.Error(), "showroom.CheckStream") {
-					return
-				}
-				select {
-				case <-time.After(backoff.DefaultPolicy.Duration(numAttempts)):
-					numAttempts++
-					// check for room again
-					_, err = e.Retry()
-					if err == nil {
-						if err = track.SnipeTargetAt(ctx, t, time.Now()); err != nil {
-							log.Println("showroom.CheckUpcoming:", err)
-						}
-					}
-				case <-timeout.C:
-					log.Println("showroom.CheckUpcoming:", name, "timeout")
-					return
-				case <-ctx.Done():
-					log.Println("showroom.CheckUpcoming:", name, ctx.Err())
-					return
+			case errors.Is(err, track.ErrTicketRequired):
+				if err = track.TicketRequired(t); err != nil {
+					log.Println("showroom.CheckUpcoming:", err)
 				}
+			case errors.Is(err, retry.ErrTimeout):
+				log.Println("showroom.CheckUpcoming:", name, "timeout")
+			case ctx.Err() != nil:
+				log.Println("showroom.CheckUpcoming:", name, ctx.Err())
 			}
 		}(tt)
 	}
@@ -167,9 +281,9 @@ func (m Module) CheckUpcoming(ctx context.Context, targets []track.Target) error
 	}()
 	select {
 	case <-done:
-		log.Println("showroom.CheckUpcoming: done")
+		log.Println("showroom.checkEachTarget: done")
 	case <-ctx.Done():
-		log.Println("showroom.CheckUpcoming:", ctx.Err())
+		log.Println("showroom.checkEachTarget:", ctx.Err())
 	}
 
 	return nil
@@ -0,0 +1,107 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"strconv"
+	"strings"
+)
+
+// kinds of streams showroom gives us
+var streamTypes = []string{"hls", "lhls"}
+
+func isStreamType(s string) bool {
+	for _, t := range streamTypes {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// selectStream gives the stream that best matches the user's preference
+// pref is a comma separated list tried in order. each entry is [type:]quality where quality is
+//
+//	best - highest quality
+//	worst - lowest quality
+//	600k - highest quality at or below a bitrate in kbps
+//	a label such as "low" that is part of the stream label
+//
+// type is hls unless given. a type alone such as "lhls" means the best stream of that type.
+// if nothing matches we fall back to the best hls stream and then the best of any type.
+func selectStream(streams []stream, pref string) (s stream, ok bool) {
+	for _, entry := range strings.Split(pref, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		kind, quality := "hls", entry
+		if isStreamType(entry) {
+			kind, quality = entry, "best"
+		} else if sp := strings.SplitN(entry, ":", 2); len(sp) == 2 {
+			kind, quality = sp[0], sp[1]
+		}
+
+		if s, ok = pickStream(streams, kind, quality); ok {
+			return
+		}
+	}
+
+	// fallback
+	if s, ok = pickStream(streams, "hls", "best"); ok {
+		return
+	}
+
+	return pickStream(streams, "", "best")
+}
+
+// kind may be empty to allow any type
+func pickStream(streams []stream, kind, quality string) (s stream, ok bool) {
+	maxRate := -1
+	if strings.HasSuffix(quality, "k") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(quality, "k")); err == nil {
+			maxRate = n
+		}
+	}
+
+	for _, c := range streams {
+		if kind != "" && c.Type != kind {
+			continue
+		}
+
+		switch {
+		case quality == "best":
+			if !ok || c.Quality > s.Quality {
+				s, ok = c, true
+			}
+		case quality == "worst":
+			if !ok || c.Quality < s.Quality {
+				s, ok = c, true
+			}
+		case maxRate >= 0:
+			if c.Quality <= maxRate && (!ok || c.Quality > s.Quality) {
+				s, ok = c, true
+			}
+		default:
+			if strings.Contains(strings.ToLower(c.Label), quality) {
+				return c, true
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,64 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"testing"
+)
+
+func TestSelectStream(t *testing.T) {
+	streams := []stream{
+		{ID: 1, Label: "original quality", Type: "hls", Quality: 1000},
+		{ID: 2, Label: "low quality", Type: "hls", Quality: 150},
+		{ID: 3, Label: "original quality", Type: "lhls", Quality: 1000},
+		{ID: 4, Label: "low quality", Type: "lhls", Quality: 150},
+	}
+
+	cases := []struct {
+		pref string
+		want int
+	}{
+		{"best", 1},
+		{"", 1},
+		{"worst", 2},
+		{"low", 2},
+		{"500k", 2},
+		{"2000k", 1},
+		{"lhls", 3},
+		{"lhls:worst", 4},
+		{"lhls:low", 4},
+		// fall back to the next preference
+		{"webrtc, worst", 2},
+		{"100k,lhls:500k", 4},
+		// nothing matches so we use the best hls stream
+		{"medium", 1},
+	}
+
+	for _, c := range cases {
+		got, ok := selectStream(streams, c.pref)
+		if !ok {
+			t.Errorf("%q: did not select a stream", c.pref)
+			continue
+		}
+		if got.ID != c.want {
+			t.Errorf("%q: want %d got %d", c.pref, c.want, got.ID)
+		}
+	}
+
+	if _, ok := selectStream(nil, "best"); ok {
+		t.Error("want no stream")
+	}
+}
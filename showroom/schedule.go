@@ -0,0 +1,112 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SHOWROOM schedules are written in Japan time
+var jst = time.FixedZone("JST", 9*60*60)
+
+// [year/]month/day[(weekday)] hour:minute
+// also 年, 月, 日 and 時 in place of the separators
+var scheduleRE = regexp.MustCompile(`(?:(\d{4})\s*[/年.-]\s*)?(\d{1,2})\s*[/月.-]\s*(\d{1,2})\s*日?\s*(?:[(（][^)）]*[)）])?\s*(\d{1,2})\s*[:時]\s*(\d{1,2})?`)
+
+// full width digits and symbols are common in schedule text
+var scheduleWidth = strings.NewReplacer(
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+	"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	"／", "/", "：", ":", "　", " ",
+)
+
+// parseSchedule reads a time written by SHOWROOM such as "6/1 20:00"
+// times without a year are in whichever year puts them closest to now
+// a schedule that is not decided yet such as "未定" or "TBD" gives a zero time
+func parseSchedule(text string, now time.Time) (at time.Time, err error) {
+	text = strings.TrimSpace(scheduleWidth.Replace(text))
+	if text == "" || isUndecided(text) {
+		return
+	}
+
+	m := scheduleRE.FindStringSubmatch(text)
+	if m == nil {
+		err = fmt.Errorf("showroom.parseSchedule: unknown format: %q", text)
+		return
+	}
+
+	num := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	month, day, hour, minute := num(m[2]), num(m[3]), num(m[4]), num(m[5])
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 47 || minute > 59 {
+		err = fmt.Errorf("showroom.parseSchedule: not a time: %q", text)
+		return
+	}
+
+	now = now.In(jst)
+	year := now.Year()
+	if m[1] != "" {
+		year = num(m[1])
+	}
+
+	// hours past 24 such as 25:00 are used for late night streams
+	at = time.Date(year, time.Month(month), day, hour, minute, 0, 0, jst)
+	if m[1] == "" {
+		// 12/31 read on 1/1 was yesterday and 1/1 read on 12/31 is tomorrow
+		for _, y := range []int{year - 1, year + 1} {
+			other := time.Date(y, time.Month(month), day, hour, minute, 0, 0, jst)
+			if distance(other, now) < distance(at, now) {
+				at = other
+			}
+		}
+	}
+
+	return at.Local(), nil
+}
+
+func distance(a, b time.Time) time.Duration {
+	if a.After(b) {
+		return a.Sub(b)
+	}
+	return b.Sub(a)
+}
+
+func isUndecided(text string) bool {
+	lower := strings.ToLower(text)
+	for _, s := range []string{"未定", "tbd", "tba", "undecided"} {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextLiveAt prefers the epoch which is exact to the second
+// the text is used when there is no epoch
+func nextLiveAt(epoch int64, text string, now time.Time) (time.Time, error) {
+	if epoch > 0 {
+		return time.Unix(epoch, 0).Local(), nil
+	}
+
+	return parseSchedule(text, now)
+}
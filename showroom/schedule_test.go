@@ -0,0 +1,76 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Date(2020, 12, 30, 12, 0, 0, 0, jst)
+
+	cases := []struct {
+		text string
+		want time.Time
+	}{
+		{"12/31 20:00", time.Date(2020, 12, 31, 20, 0, 0, 0, jst)},
+		{"1/2 21:30", time.Date(2021, 1, 2, 21, 30, 0, 0, jst)},
+		{"12/29 20:00", time.Date(2020, 12, 29, 20, 0, 0, 0, jst)},
+		{"2021/3/4 9:05", time.Date(2021, 3, 4, 9, 5, 0, 0, jst)},
+		{"1月5日(火) 19:00", time.Date(2021, 1, 5, 19, 0, 0, 0, jst)},
+		{"１２／３１　２０：００", time.Date(2020, 12, 31, 20, 0, 0, 0, jst)},
+		{"12/31 25:00", time.Date(2021, 1, 1, 1, 0, 0, 0, jst)},
+		{"12月31日 20時", time.Date(2020, 12, 31, 20, 0, 0, 0, jst)},
+		{"未定", time.Time{}},
+		{"TBD", time.Time{}},
+		{"", time.Time{}},
+	}
+
+	for _, c := range cases {
+		got, err := parseSchedule(c.text, now)
+		if err != nil {
+			t.Errorf("parseSchedule(%q): %s", c.text, err)
+			continue
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("parseSchedule(%q) = %s; want %s", c.text, got, c.want)
+		}
+	}
+
+	// a date late in the year read early in the next one is in the past
+	january := time.Date(2021, 1, 1, 12, 0, 0, 0, jst)
+	if got, _ := parseSchedule("12/31 20:00", january); !got.Equal(time.Date(2020, 12, 31, 20, 0, 0, 0, jst)) {
+		t.Errorf("expected last year: %s", got)
+	}
+
+	if _, err := parseSchedule("tonight", now); err == nil {
+		t.Error("expected an error for text without a time")
+	}
+}
+
+func TestNextLiveAtPrefersEpoch(t *testing.T) {
+	now := time.Date(2020, 12, 30, 12, 0, 0, 0, jst)
+	epoch := time.Date(2020, 12, 31, 20, 0, 30, 0, jst).Unix()
+
+	at, err := nextLiveAt(epoch, "12/31 20:00", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at.Unix() != epoch {
+		t.Errorf("expected the epoch to be used: %s", at)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"log"
 	"path"
 	"runtime"
-	"strings"
 	"sync"
 	"time"
 
@@ -122,7 +121,7 @@ func scrapeRoomData(ctx context.Context, link string) (roomStatus, error) {
 	check(err)
 
 	// confirm room url key
-	if strings.ToLower(status.LiveRoom.URLKey) != strings.ToLower(expectedURLKey) {
+	if roomKey(status.LiveRoom.URLKey) != roomKey(expectedURLKey) {
 		return roomStatus{}, fmt.Errorf("unexpected room url key")
 	}
 
@@ -0,0 +1,312 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+// login details kept in the config directory
+// only the user should be able to read it
+const loginFilename = "showroom-login.json"
+
+type showroomLogin struct {
+	AccountID string `json:"account_id"`
+	Password  string `json:"password"`
+}
+
+type csrfTokenResponse struct {
+	Token string `json:"csrf_token"`
+}
+
+type loginResponse struct {
+	OK    int    `json:"ok"`
+	Error string `json:"error"`
+}
+
+var session = struct {
+	sync.Mutex
+	// the cookie file we loaded and when it was changed
+	cookiesPath  string
+	cookiesModAt time.Time
+	// true once we tried to log in
+	triedLogin bool
+}{}
+
+// ensureSession loads the user's SHOWROOM session into our cookie jar
+// a cookie file is loaded again if it changes
+// otherwise we log in once with the login details in the config directory
+func ensureSession(ctx context.Context) {
+	session.Lock()
+	defer session.Unlock()
+
+	if p := options.Get("showroom_cookies"); p != "" {
+		fi, err := os.Stat(p)
+		if err != nil {
+			log.Println("showroom.ensureSession:", err)
+			return
+		}
+		if p == session.cookiesPath && fi.ModTime().Equal(session.cookiesModAt) {
+			return
+		}
+		session.cookiesPath = p
+		session.cookiesModAt = fi.ModTime()
+
+		n, err := loadCookieFile(p)
+		if err != nil {
+			log.Println("showroom.ensureSession:", err)
+			return
+		}
+		log.Println("showroom.ensureSession: loaded", n, "cookies from", p)
+		return
+	}
+
+	if session.triedLogin {
+		return
+	}
+	session.triedLogin = true
+
+	login, err := readLogin(filepath.Join(options.ConfigPath, loginFilename))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Println("showroom.ensureSession:", err)
+		return
+	}
+
+	if err := logIn(ctx, login); err != nil {
+		log.Println("showroom.ensureSession:", err)
+		return
+	}
+	log.Println("showroom.ensureSession: logged in as", login.AccountID)
+}
+
+// reads login details
+// refuses a file that others can read
+func readLogin(p string) (login showroomLogin, err error) {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0077 != 0 {
+		err = fmt.Errorf("showroom.readLogin: %s can be read by others (chmod 600 %s)", p, p)
+		return
+	}
+
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		err = fmt.Errorf("showroom.readLogin: %s", err)
+		return
+	}
+	if err = json.Unmarshal(data, &login); err != nil {
+		err = fmt.Errorf("showroom.readLogin: %s", err)
+		return
+	}
+	if login.AccountID == "" || login.Password == "" {
+		err = fmt.Errorf("showroom.readLogin: %s needs an account_id and password", p)
+		return
+	}
+
+	return
+}
+
+// logs in and keeps the session cookies in our cookie jar
+func logIn(ctx context.Context, login showroomLogin) error {
+	req, err := makeRequest(ctx, "GET", apiURL("/api/csrf_token"), nil, "")
+	if err != nil {
+		return fmt.Errorf("showroom.logIn: %s", err)
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("showroom.logIn: %s", err)
+	}
+	buf, err := readResponse(res)
+	res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("showroom.logIn: %s", err)
+	}
+	var token csrfTokenResponse
+	if err := json.Unmarshal(buf.Bytes(), &token); err != nil {
+		return fmt.Errorf("showroom.logIn: %s", err)
+	}
+
+	form := url.Values{
+		"csrf_token": {token.Token},
+		"account_id": {login.AccountID},
+		"password":   {login.Password},
+	}
+	req, err = makeRequest(ctx, "POST", apiURL("/user/login"), strings.NewReader(form.Encode()), "https://www.showroom-live.com/")
+	if err != nil {
+		return fmt.Errorf("showroom.logIn: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err = httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("showroom.logIn: %s", err)
+	}
+	defer res.Body.Close()
+	buf, err = readResponse(res)
+	if err != nil {
+		return fmt.Errorf("showroom.logIn: %s", err)
+	}
+
+	var data loginResponse
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		return fmt.Errorf("showroom.logIn: %s", err)
+	}
+	if data.OK != 1 {
+		if data.Error == "" {
+			data.Error = "login failed"
+		}
+		return fmt.Errorf("showroom.logIn: %s", data.Error)
+	}
+
+	return nil
+}
+
+// loads cookies exported by a browser into our cookie jar
+func loadCookieFile(p string) (n int, err error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return 0, fmt.Errorf("showroom.loadCookieFile: %s", err)
+	}
+	defer f.Close()
+
+	cookies, err := parseCookieFile(f, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range cookies {
+		u := url.URL{
+			Scheme: "https",
+			Host:   strings.TrimPrefix(c.Domain, "."),
+			Path:   c.Path,
+		}
+		if !strings.HasPrefix(c.Domain, ".") {
+			// host only
+			c.Domain = ""
+		}
+		httpCookieJar.SetCookies(&u, []*http.Cookie{c})
+	}
+
+	return len(cookies), nil
+}
+
+// parseCookieFile reads cookies in the Netscape format used by curl and browser extensions
+// each line is
+//
+//	domain	include subdomains	path	secure	expires	name	value
+//
+// expired cookies are left out
+func parseCookieFile(r io.Reader, now time.Time) (cookies []*http.Cookie, err error) {
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("showroom.parseCookieFile: line %d: expected 7 fields", lineno)
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("showroom.parseCookieFile: line %d: %s", lineno, err)
+		}
+
+		c := &http.Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		if expires != 0 {
+			c.Expires = time.Unix(expires, 0)
+			if c.Expires.Before(now) {
+				continue
+			}
+		}
+		if !strings.EqualFold(fields[1], "TRUE") {
+			c.Domain = strings.TrimPrefix(c.Domain, ".")
+		} else if !strings.HasPrefix(c.Domain, ".") {
+			c.Domain = "." + c.Domain
+		}
+
+		cookies = append(cookies, c)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("showroom.parseCookieFile: %s", err)
+	}
+
+	return
+}
+
+// gives a Cookie header with our cookies for a url
+func cookieHeader(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+
+	var pairs []string
+	for _, c := range httpCookieJar.Cookies(u) {
+		pairs = append(pairs, c.Name+"="+c.Value)
+	}
+
+	return strings.Join(pairs, "; ")
+}
+
+// true if the room is live but needs a ticket or membership to watch
+func isTicketed(ctx context.Context, id int) (bool, error) {
+	data, err := checkRoomProfile(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return data.IsOnlive && data.PremiumRoomType != 0, nil
+}
@@ -0,0 +1,55 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroom
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCookieFile(t *testing.T) {
+	file := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"",
+		".showroom-live.com\tTRUE\t/\tTRUE\t1900000000\tsr_id\tabc",
+		"#HttpOnly_www.showroom-live.com\tFALSE\t/\tFALSE\t0\tsession\txyz",
+		".showroom-live.com\tTRUE\t/\tFALSE\t1000\told\tgone",
+	}, "\n")
+
+	now := time.Unix(1600000000, 0)
+	cookies, err := parseCookieFile(strings.NewReader(file), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("expected 2 cookies but got %d", len(cookies))
+	}
+
+	c := cookies[0]
+	if c.Name != "sr_id" || c.Value != "abc" || c.Domain != ".showroom-live.com" || !c.Secure {
+		t.Errorf("unexpected cookie: %+v", c)
+	}
+
+	c = cookies[1]
+	if c.Name != "session" || !c.HttpOnly || c.Domain != "www.showroom-live.com" || !c.Expires.IsZero() {
+		t.Errorf("unexpected cookie: %+v", c)
+	}
+
+	if _, err := parseCookieFile(strings.NewReader("bad line"), now); err == nil {
+		t.Error("expected an error for a bad line")
+	}
+}
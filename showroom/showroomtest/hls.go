@@ -0,0 +1,125 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package showroomtest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// each segment is this many transport stream packets
+const packetsPerSegment = 16
+
+const packetSize = 188
+
+// gives the room and the segments that exist for a live
+// ok is false if the live is not the room's latest
+func (s *Server) liveSegments(id, liveID int) (first, last int, ended bool, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, found := s.rooms[id]
+	if !found || room.liveID == 0 || room.liveID != liveID {
+		return
+	}
+
+	until := s.Now()
+	if !room.live {
+		until = room.endedAt
+		ended = true
+	}
+	last = int(until.Sub(room.startedAt) / SegmentDuration)
+	first = last - playlistWindow + 1
+	if first < 0 {
+		first = 0
+	}
+
+	return first, last, ended, true
+}
+
+// serves /hls/ROOM_ID/LIVE_ID/index.m3u8 and /hls/ROOM_ID/LIVE_ID/SEQUENCE.ts
+func (s *Server) hls(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/hls/"), "/")
+	if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+	id, err1 := strconv.Atoi(parts[0])
+	liveID, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	first, last, ended, ok := s.liveSegments(id, liveID)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if parts[2] == "index.m3u8" {
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.Write(playlist(first, last, ended))
+		return
+	}
+
+	seq, err := strconv.Atoi(strings.TrimSuffix(parts[2], ".ts"))
+	if err != nil || !strings.HasSuffix(parts[2], ".ts") || seq < 0 || seq > last {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.Write(Segment(seq))
+}
+
+func playlist(first, last int, ended bool) []byte {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "#EXTM3U")
+	fmt.Fprintln(&b, "#EXT-X-VERSION:3")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(SegmentDuration/time.Second))
+	fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", first)
+	for seq := first; seq <= last; seq++ {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", SegmentDuration.Seconds())
+		fmt.Fprintf(&b, "%d.ts\n", seq)
+	}
+	if ended {
+		fmt.Fprintln(&b, "#EXT-X-ENDLIST")
+	}
+	return b.Bytes()
+}
+
+// Segment gives the generated segment for a sequence number
+// it is made of null transport stream packets filled with the sequence number
+// so a test can tell which segments were saved
+func Segment(seq int) []byte {
+	seg := make([]byte, 0, packetsPerSegment*packetSize)
+	for n := 0; n < packetsPerSegment; n++ {
+		packet := make([]byte, packetSize)
+		packet[0] = 0x47
+		packet[1] = 0x1f
+		packet[2] = 0xff
+		packet[3] = 0x10 | byte(n&0x0f)
+		for ndx := 4; ndx < packetSize; ndx++ {
+			packet[ndx] = byte(seq)
+		}
+		seg = append(seg, packet...)
+	}
+	return seg
+}
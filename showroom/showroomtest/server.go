@@ -0,0 +1,405 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package showroomtest is a pretend SHOWROOM for tests
+//
+// Point autosr at it with the 'showroom_api' option.
+// Rooms go live and offline when the test says so and a live room serves
+// an HLS playlist with generated segments so a recorder has something to save.
+// The broadcast server for comments is not pretended.
+package showroomtest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SegmentDuration is how long each generated segment lasts
+const SegmentDuration = 2 * time.Second
+
+// how many segments a live playlist lists
+const playlistWindow = 5
+
+// Room on the pretend SHOWROOM
+type Room struct {
+	ID     int
+	URLKey string
+	Name   string
+	// needs a ticket to watch while live
+	Premium bool
+
+	live         bool
+	liveID       int
+	startedAt    time.Time
+	endedAt      time.Time
+	telop        string
+	nextLive     time.Time
+	nextLiveText string
+}
+
+type failure struct {
+	times      int
+	status     int
+	retryAfter time.Duration
+}
+
+// Server is a pretend SHOWROOM
+type Server struct {
+	*httptest.Server
+
+	// Now gives the time used for lives and segments
+	Now func() time.Time
+
+	mu       sync.Mutex
+	rooms    map[int]*Room
+	gzip     bool
+	failures map[string]*failure
+	requests map[string]int
+	lastLive int
+}
+
+// NewServer starts a pretend SHOWROOM
+// Close it when the test is done
+func NewServer() *Server {
+	s := &Server{
+		Now:      time.Now,
+		rooms:    make(map[int]*Room),
+		failures: make(map[string]*failure),
+		requests: make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/room/status", s.roomStatus)
+	mux.HandleFunc("/api/room/profile", s.roomProfile)
+	mux.HandleFunc("/room/is_live", s.isLive)
+	mux.HandleFunc("/api/live/streaming_url", s.streamingURL)
+	mux.HandleFunc("/api/room/next_live", s.nextLive)
+	mux.HandleFunc("/api/live/onlives", s.onlives)
+	mux.HandleFunc("/api/live/live_info", s.liveInfo)
+	mux.HandleFunc("/api/live/telop", s.telop)
+	mux.HandleFunc("/hls/", s.hls)
+	s.Server = httptest.NewServer(s.handle(mux))
+
+	return s
+}
+
+// AddRoom that is offline
+func (s *Server) AddRoom(id int, urlKey, name string) *Room {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &Room{ID: id, URLKey: urlKey, Name: name}
+	s.rooms[id] = r
+	return r
+}
+
+// Link to a room as a user would write it
+func (s *Server) Link(id int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return "https://www.showroom-live.com/" + s.rooms[id].URLKey
+}
+
+// GoLive begins a new live for a room
+func (s *Server) GoLive(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.rooms[id]
+	s.lastLive++
+	r.live = true
+	r.liveID = s.lastLive
+	r.startedAt = s.Now()
+	r.endedAt = time.Time{}
+	r.nextLive = time.Time{}
+	r.nextLiveText = ""
+}
+
+// EndLive ends the live for a room
+// the playlist is ended so a recorder sees the stream finish
+func (s *Server) EndLive(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.rooms[id]
+	r.live = false
+	r.endedAt = s.Now()
+}
+
+// SetNextLive announces the next live for a room
+// a zero time gives only the text as some rooms do
+func (s *Server) SetNextLive(id int, at time.Time, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.rooms[id]
+	r.nextLive = at
+	r.nextLiveText = text
+}
+
+// SetTelop changes the text shown over a live
+func (s *Server) SetTelop(id int, telop string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rooms[id].telop = telop
+}
+
+// SetGzip makes every api response gzip encoded
+func (s *Server) SetGzip(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gzip = on
+}
+
+// Fail the next requests to a path with the given status
+func (s *Server) Fail(path string, times int, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = &failure{times: times, status: status}
+}
+
+// Throttle the next requests to a path with 429 Too Many Requests
+// asking us to wait retryAfter
+func (s *Server) Throttle(path string, times int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = &failure{times: times, status: http.StatusTooManyRequests, retryAfter: retryAfter}
+}
+
+// Requests gives how many requests were made to a path
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// counts requests and fails the ones we were told to
+func (s *Server) handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		f := s.failures[r.URL.Path]
+		fail := f != nil && f.times > 0
+		if fail {
+			f.times--
+		}
+		s.mu.Unlock()
+
+		if fail {
+			if f.retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(f.retryAfter/time.Second)))
+			}
+			http.Error(w, http.StatusText(f.status), f.status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	s.mu.Lock()
+	useGzip := s.gzip && strings.Contains(r.Header.Get("Accept-Encoding"), "gzip")
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !useGzip {
+		json.NewEncoder(w).Encode(v)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	gz := gzip.NewWriter(w)
+	defer gz.Close()
+	json.NewEncoder(gz).Encode(v)
+}
+
+// gives the room asked for by room_id
+func (s *Server) room(r *http.Request) (room Room, ok bool) {
+	id, _ := strconv.Atoi(r.URL.Query().Get("room_id"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if found, ok := s.rooms[id]; ok {
+		return *found, true
+	}
+	return
+}
+
+func (s *Server) roomStatus(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("room_url_key")
+	s.mu.Lock()
+	var found *Room
+	for _, room := range s.rooms {
+		if strings.EqualFold(room.URLKey, key) {
+			copied := *room
+			found = &copied
+		}
+	}
+	s.mu.Unlock()
+
+	if found == nil {
+		s.writeJSON(w, r, map[string]interface{}{})
+		return
+	}
+	s.writeJSON(w, r, map[string]interface{}{
+		"room_id":      found.ID,
+		"room_url_key": found.URLKey,
+		"room_name":    found.Name,
+		"is_live":      found.live,
+		"started_at":   unix(found.startedAt, found.live),
+		"live_id":      found.liveID,
+	})
+}
+
+func (s *Server) roomProfile(w http.ResponseWriter, r *http.Request) {
+	room, ok := s.room(r)
+	if !ok {
+		s.writeJSON(w, r, map[string]interface{}{})
+		return
+	}
+
+	premium := 0
+	if room.Premium {
+		premium = 1
+	}
+	s.writeJSON(w, r, map[string]interface{}{
+		"room_name":               room.Name,
+		"room_url_key":            room.URLKey,
+		"main_name":               room.Name,
+		"is_onlive":               room.live,
+		"current_live_started_at": unix(room.startedAt, room.live),
+		"live_id":                 room.liveID,
+		"premium_room_type":       premium,
+	})
+}
+
+func (s *Server) isLive(w http.ResponseWriter, r *http.Request) {
+	room, _ := s.room(r)
+	ok := 0
+	if room.live {
+		ok = 1
+	}
+	s.writeJSON(w, r, map[string]int{"ok": ok})
+}
+
+func (s *Server) streams(room Room) []map[string]interface{} {
+	if !room.live || room.Premium {
+		return nil
+	}
+
+	playlist := fmt.Sprintf("%s/hls/%d/%d/index.m3u8", s.URL, room.ID, room.liveID)
+	return []map[string]interface{}{
+		{"id": 1, "is_default": true, "label": "original quality", "type": "hls", "url": playlist, "quality": 1000},
+		{"id": 2, "is_default": false, "label": "low quality", "type": "hls", "url": playlist + "?low=1", "quality": 100},
+	}
+}
+
+func (s *Server) streamingURL(w http.ResponseWriter, r *http.Request) {
+	room, _ := s.room(r)
+	list := s.streams(room)
+	if list == nil {
+		// SHOWROOM gives an empty object for a room that is not live
+		s.writeJSON(w, r, map[string]interface{}{})
+		return
+	}
+	s.writeJSON(w, r, map[string]interface{}{"streaming_url_list": list})
+}
+
+func (s *Server) nextLive(w http.ResponseWriter, r *http.Request) {
+	room, _ := s.room(r)
+	text := room.nextLiveText
+	if text == "" {
+		text = "未定"
+	}
+	s.writeJSON(w, r, map[string]interface{}{
+		"epoch": unix(room.nextLive, !room.nextLive.IsZero()),
+		"text":  text,
+	})
+}
+
+func (s *Server) onlives(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var lives []map[string]interface{}
+	for _, room := range s.rooms {
+		if !room.live {
+			continue
+		}
+		lives = append(lives, map[string]interface{}{
+			"room_id":            room.ID,
+			"room_url_key":       room.URLKey,
+			"main_name":          room.Name,
+			"bcsvr_key":          bcsvrKey(*room),
+			"live_id":            room.liveID,
+			"started_at":         room.startedAt.Unix(),
+			"telop":              room.telop,
+			"streaming_url_list": s.streams(*room),
+		})
+	}
+	s.mu.Unlock()
+
+	host, port := s.hostPort()
+	s.writeJSON(w, r, map[string]interface{}{
+		"bcsvr_host": host,
+		"bcsvr_port": port,
+		"onlives": []map[string]interface{}{
+			{"genre_id": 0, "genre_name": "Popularity", "lives": lives},
+		},
+	})
+}
+
+func (s *Server) liveInfo(w http.ResponseWriter, r *http.Request) {
+	room, _ := s.room(r)
+	key := ""
+	status := 1
+	if room.live {
+		key = bcsvrKey(room)
+		status = 2
+	}
+	host, port := s.hostPort()
+	s.writeJSON(w, r, map[string]interface{}{
+		"live_id":     room.liveID,
+		"live_status": status,
+		"room_id":     room.ID,
+		"room_name":   room.Name,
+		"bcsvr_key":   key,
+		"bcsvr_host":  host,
+		"bcsvr_port":  port,
+	})
+}
+
+func (s *Server) telop(w http.ResponseWriter, r *http.Request) {
+	room, _ := s.room(r)
+	s.writeJSON(w, r, map[string]string{"telop": room.telop})
+}
+
+func (s *Server) hostPort() (string, int) {
+	addr := strings.TrimPrefix(s.URL, "http://")
+	sp := strings.SplitN(addr, ":", 2)
+	port, _ := strconv.Atoi(sp[1])
+	return sp[0], port
+}
+
+func bcsvrKey(room Room) string {
+	return fmt.Sprintf("%x:%d", room.ID, room.liveID)
+}
+
+func unix(t time.Time, ok bool) int64 {
+	if !ok || t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -17,23 +17,54 @@ package showroom
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"path"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/bobbytrapz/autosr/backoff"
 	"github.com/bobbytrapz/autosr/retry"
 	"github.com/bobbytrapz/autosr/track"
 )
 
+// a room that is not streaming yet may be asked again later
+var errNoStream = errors.New("no stream yet")
+
 type target struct {
 	// info
-	name     string
-	display  string
-	id       int
-	link     string
-	urlKey   string
-	bcsvrKey string
+	name    string
+	display string
+	id      int
+	link    string
+	urlKey  string
+
+	// broadcast server and stream change each live
+	liveMu sync.Mutex
+	bcsvr  broadcastServer
+	stream stream
+	// from the list of live rooms
+	room room
+	// when we last asked for their upcoming time
+	nextLiveCheckedAt time.Time
+	// the live we last asked about a ticket for
+	ticketLiveID int
+	ticketed     bool
+	// about the live we are saving
+	details       liveDetails
+	detailsCancel context.CancelFunc
+	detailsDone   <-chan struct{}
+	// true while we are connected to the broadcast server
+	connected bool
+
+	// we listen to the broadcast server while we save
+	// chat is recorded if the user asked for it
+	chatMu       sync.Mutex
+	chat         *chatRecorder
+	eventsCancel context.CancelFunc
+	eventsDone   <-chan struct{}
 }
 
 func (t *target) updateInfo(ctx context.Context) error {
@@ -41,7 +72,127 @@ func (t *target) updateInfo(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("showroom.UpdateInfo: %s", err)
 	}
-	*t = *info
+	t.name = info.name
+	t.display = info.display
+	t.id = info.id
+	t.urlKey = info.urlKey
+	return nil
+}
+
+func (t *target) broadcastServer() broadcastServer {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	return t.bcsvr
+}
+
+func (t *target) setBroadcastServer(bcsvr broadcastServer) {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	t.bcsvr = bcsvr
+}
+
+// details about the room from the list of live rooms
+func (t *target) liveRoom() room {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	return t.room
+}
+
+// LiveID is the id of the live we saw last
+func (t *target) LiveID() string {
+	if r := t.liveRoom(); r.ID == t.id && r.LiveID != 0 {
+		return strconv.Itoa(r.LiveID)
+	}
+	return ""
+}
+
+func (t *target) setLiveRoom(r room, bcsvr broadcastServer) {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	t.room = r
+	if bcsvr.Key != "" {
+		t.bcsvr = bcsvr
+	}
+}
+
+// true if we have not asked for their upcoming time for a while
+func (t *target) shouldCheckNextLive() bool {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	return time.Since(t.nextLiveCheckedAt) > nextLiveEvery
+}
+
+// snipe the target if they have an upcoming time set
+func (t *target) checkNextLive(ctx context.Context) error {
+	at, err := checkNextLive(ctx, t.id)
+	if err != nil {
+		return err
+	}
+
+	t.liveMu.Lock()
+	t.nextLiveCheckedAt = time.Now()
+	t.liveMu.Unlock()
+
+	if at.IsZero() {
+		return nil
+	}
+
+	return track.SnipeTargetAt(ctx, t, at)
+}
+
+// true if their current live needs a ticket
+// we only ask once they are in the list of live rooms and only once for each live
+func (t *target) needsTicket(ctx context.Context) (bool, error) {
+	live, _, err := liveRooms(ctx)
+	if err != nil {
+		return false, err
+	}
+	r, isLive := live[t.id]
+	if !isLive {
+		return false, nil
+	}
+
+	t.liveMu.Lock()
+	if r.LiveID != 0 && r.LiveID == t.ticketLiveID {
+		ticketed := t.ticketed
+		t.liveMu.Unlock()
+		return ticketed, nil
+	}
+	t.liveMu.Unlock()
+
+	ticketed, err := isTicketed(ctx, t.id)
+	if err != nil {
+		return false, err
+	}
+
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	t.ticketLiveID = r.LiveID
+	t.ticketed = ticketed
+
+	return ticketed, nil
+}
+
+// the stream we chose most recently
+func (t *target) chosenStream() stream {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	return t.stream
+}
+
+func (t *target) setStream(s stream) {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	t.stream = s
+}
+
+// the broadcast server is only known while the target is live
+func (t *target) refreshBroadcastServer(ctx context.Context) error {
+	bcsvr, err := checkBroadcastServer(ctx, t.id)
+	if err != nil {
+		return err
+	}
+	t.setBroadcastServer(bcsvr)
 	return nil
 }
 
@@ -67,12 +218,30 @@ func (t *target) BeginSnipe(ctx context.Context) {
 func (t *target) BeginSave(ctx context.Context) {
 	log.Println("showroom.BeginSave:", t.name)
 
-	if ShouldWatchEvents {
-		err := WatchEvents(ctx, t.bcsvrKey)
-		if err != nil {
-			log.Println("showroom.WatchEvents:", t.name)
+	// they are live now so this is when we can find their broadcast server
+	if err := t.refreshBroadcastServer(ctx); err != nil {
+		log.Println("showroom.BeginSave:", t.name, err)
+	}
+
+	t.setLiveDetails(t.checkLiveDetails(ctx))
+
+	var rec *chatRecorder
+	if track.OptionBool(t.link, "record_chat") {
+		var err error
+		if rec, err = t.newChatRecorder(ctx); err != nil {
+			log.Println("showroom.BeginSave:", t.name, err)
 		}
 	}
+	t.beginEvents(ctx, rec)
+
+	if saveAs, ok := track.SavePathFromContext(ctx); ok {
+		detailsCtx, cancel := context.WithCancel(ctx)
+		done := t.watchLiveDetails(detailsCtx, saveAs)
+		t.liveMu.Lock()
+		t.detailsCancel = cancel
+		t.detailsDone = done
+		t.liveMu.Unlock()
+	}
 
 	return
 }
@@ -80,9 +249,159 @@ func (t *target) BeginSave(ctx context.Context) {
 // EndSave callback
 func (t *target) EndSave(_ context.Context) {
 	log.Println("showroom.EndSave:", t.name)
+	t.endEvents()
+	t.endLiveDetails()
 	return
 }
 
+// stop keeping the live details up to date
+func (t *target) endLiveDetails() {
+	t.liveMu.Lock()
+	cancel, done := t.detailsCancel, t.detailsDone
+	t.detailsCancel = nil
+	t.detailsDone = nil
+	t.liveMu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// record chat next to the recording
+func (t *target) newChatRecorder(ctx context.Context) (*chatRecorder, error) {
+	saveAs, ok := track.SavePathFromContext(ctx)
+	if !ok {
+		return nil, errors.New("showroom.newChatRecorder: we do not know where we are saving")
+	}
+
+	return newChatRecorder(saveAs, time.Now(), track.Option(t.link, "chat_subtitles"))
+}
+
+// listen to the broadcast server while we save
+// telop changes are always followed and chat is recorded if rec is not nil
+func (t *target) beginEvents(ctx context.Context, rec *chatRecorder) {
+	eventsCtx, cancel := context.WithCancel(ctx)
+	done := t.watchChat(eventsCtx, func(msg []byte) {
+		ev, err := parseEvent(msg)
+		if err != nil {
+			log.Println("showroom.beginEvents:", err)
+			return
+		}
+
+		if telop, ok := ev.(Telop); ok {
+			t.updateTelop(telop.Text, telop.At)
+			return
+		}
+
+		if rec == nil {
+			return
+		}
+		if err := rec.Record(ev); err != nil {
+			log.Println("showroom.beginEvents:", err)
+		}
+	})
+
+	t.chatMu.Lock()
+	defer t.chatMu.Unlock()
+	t.chat = rec
+	t.eventsCancel = cancel
+	t.eventsDone = done
+}
+
+// keeps us connected to the broadcast server until ctx is done
+func (t *target) watchChat(ctx context.Context, handle func([]byte)) <-chan struct{} {
+	done := make(chan struct{})
+
+	track.Add(1)
+	go func() {
+		defer track.Done()
+		defer close(done)
+		defer t.setEventsConnected(false)
+
+		b := backoff.For(module.Hostname(), backoff.Recover).New()
+		for {
+			var err error
+			if bcsvr := t.broadcastServer(); bcsvr.Key == "" {
+				err = errors.New("we do not have a broadcast key")
+			} else {
+				var watchDone <-chan struct{}
+				connectedAt := time.Now()
+				watchDone, err = WatchEvents(ctx, bcsvr, handle)
+				if err == nil {
+					// wait until the connection drops
+					t.setEventsConnected(true)
+					<-watchDone
+					t.setEventsConnected(false)
+					if time.Since(connectedAt) > time.Minute {
+						b.Reset()
+					}
+				}
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Println("showroom.watchChat:", t.name, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(b.Next()):
+			}
+
+			// the key changes if the live was restarted
+			log.Println("showroom.watchChat:", t.name, "reconnecting...")
+			if err := t.refreshBroadcastServer(ctx); err != nil {
+				log.Println("showroom.watchChat:", t.name, err)
+			}
+		}
+	}()
+
+	return done
+}
+
+// true while we are connected to the broadcast server
+func (t *target) eventsConnected() bool {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	return t.connected
+}
+
+func (t *target) setEventsConnected(connected bool) {
+	t.liveMu.Lock()
+	defer t.liveMu.Unlock()
+	t.connected = connected
+}
+
+// stop listening to the broadcast server and close the chat files
+func (t *target) endEvents() {
+	t.chatMu.Lock()
+	defer t.chatMu.Unlock()
+	if t.eventsCancel == nil {
+		return
+	}
+
+	t.eventsCancel()
+	select {
+	case <-t.eventsDone:
+	case <-time.After(5 * time.Second):
+		log.Println("showroom.endEvents:", t.name, "timeout waiting for the broadcast server to close")
+	}
+
+	if t.chat != nil {
+		if err := t.chat.Close(); err != nil {
+			log.Println("showroom.endEvents:", t.name, err)
+		}
+	}
+	t.chat = nil
+	t.eventsCancel = nil
+	t.eventsDone = nil
+}
+
 // Display for display in dashboard
 func (t *target) Display() string {
 	return t.display
@@ -103,12 +422,7 @@ func (t *target) CheckLive(ctx context.Context) (isLive bool, err error) {
 	// check to see if the user is live
 	isLive, err = checkIsLive(ctx, t.id)
 	if err == nil && !isLive {
-		err = retry.BoolError{
-			Message: fmt.Sprintf("%s is not live yet", t.name),
-			Attempt: func() (bool, error) {
-				return t.CheckLive(ctx)
-			},
-		}
+		err = retry.Temporary(fmt.Errorf("%s is %w", t.name, track.ErrNotLive))
 	}
 
 	return
@@ -125,13 +439,37 @@ func (t *target) CheckStream(ctx context.Context) (streamURL string, err error)
 	}
 
 	// check for stream
-	if s, err := checkStreamURL(ctx, t.id); err == nil && s != "" {
-		return s, nil
+	pref := track.Option(t.link, "stream_quality")
+	s, err := checkStreamURL(ctx, t.id, pref)
+	if retry.IsTemporary(err) {
+		// the site may have asked us to slow down so we do not ask it anything else
+		return "", fmt.Errorf("showroom.CheckStream: %s: %w", t.name, err)
+	}
+	if err == nil && s.URL != "" {
+		log.Printf("showroom.CheckStream: %s chose %s %q (%d)\n", t.name, s.Type, s.Label, s.Quality)
+		t.setStream(s)
+		// a new live may have a new broadcast server such as when we recover
+		if err := t.refreshBroadcastServer(ctx); err != nil {
+			log.Println("showroom.CheckStream:", err)
+		}
+		return s.URL, nil
+	}
+
+	// premium lives do not give a stream unless we have a ticket
+	if ticketed, err := t.needsTicket(ctx); err == nil && ticketed {
+		return "", fmt.Errorf("showroom.CheckStream: %s: %w", t.name, track.ErrTicketRequired)
+	} else if retry.IsTemporary(err) {
+		return "", fmt.Errorf("showroom.CheckStream: %s: %w", t.name, err)
+	} else if err != nil {
+		log.Println("showroom.CheckStream:", err)
 	}
 
 	// check for upcoming time
 	var at time.Time
 	if at, err = checkNextLive(ctx, t.id); err == nil && !at.IsZero() {
+		t.liveMu.Lock()
+		t.nextLiveCheckedAt = time.Now()
+		t.liveMu.Unlock()
 		// there's a date set so maybe add a snipe
 		if err = track.SnipeTargetAt(ctx, t, at); err != nil {
 			log.Println("showroom.CheckStream:", err)
@@ -144,16 +482,34 @@ func (t *target) CheckStream(ctx context.Context) (streamURL string, err error)
 		return
 	}
 
-	err = retry.StringError{
-		Message: fmt.Sprintf("%s has no stream yet", t.name),
-		Attempt: func() (string, error) {
-			return t.CheckStream(ctx)
-		},
-	}
+	err = retry.Temporary(fmt.Errorf("%s has %w", t.name, errNoStream))
 
 	return
 }
 
+// Cookies gives our session cookies for premium streams
+func (t *target) Cookies(streamURL string) string {
+	return cookieHeader(streamURL)
+}
+
+// Describe adds details to the save metadata
+func (t *target) Describe() map[string]interface{} {
+	s := t.chosenStream()
+	d := t.liveDetails()
+	return map[string]interface{}{
+		"room_id":      t.id,
+		"room_url_key": t.urlKey,
+		"live":         d,
+		"chapters":     d.chapters(),
+		"stream": map[string]interface{}{
+			"id":      s.ID,
+			"label":   s.Label,
+			"type":    s.Type,
+			"quality": s.Quality,
+		},
+	}
+}
+
 // SavePath decides where videos are saved
 func (t *target) SavePath() string {
 	if t.name != "" {
@@ -0,0 +1,73 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package stats
+
+import (
+	"time"
+)
+
+// a start time must be seen this many times before we expect it again
+const minOccurrences = 2
+
+// Window is an hour of the week in which a target often begins streaming
+type Window struct {
+	Weekday time.Weekday
+	Hour    int
+	// number of streams that started in this window
+	Count int
+}
+
+// Contains is true if at is within the window or lead before it begins
+func (w Window) Contains(at time.Time, lead time.Duration) bool {
+	// find the most recent time the window began as seen from lead ahead of us
+	ahead := at.Local().Add(lead)
+	days := (int(ahead.Weekday()) - int(w.Weekday) + 7) % 7
+	begin := time.Date(ahead.Year(), ahead.Month(), ahead.Day()-days, w.Hour, 0, 0, 0, ahead.Location())
+	if begin.After(ahead) {
+		begin = begin.AddDate(0, 0, -7)
+	}
+
+	return at.Before(begin.Add(time.Hour))
+}
+
+// Predict the windows in which each target is likely to begin streaming
+func Predict(sessions []Session) map[string][]Window {
+	type bucket struct {
+		link    string
+		weekday time.Weekday
+		hour    int
+	}
+
+	counts := make(map[bucket]int)
+	for _, s := range sessions {
+		at := s.StartedAt.Local()
+		counts[bucket{s.Link, at.Weekday(), at.Hour()}]++
+	}
+
+	windows := make(map[string][]Window)
+	for b, n := range counts {
+		if n < minOccurrences {
+			continue
+		}
+		windows[b.link] = append(windows[b.link], Window{
+			Weekday: b.weekday,
+			Hour:    b.hour,
+			Count:   n,
+		})
+	}
+
+	return windows
+}
@@ -0,0 +1,53 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowContains(t *testing.T) {
+	// monday at 20:00
+	w := Window{Weekday: time.Monday, Hour: 20}
+	monday := time.Date(2021, 2, 1, 0, 0, 0, 0, time.Local)
+	lead := 10 * time.Minute
+
+	cases := []struct {
+		at   time.Time
+		want bool
+	}{
+		{monday.Add(19 * time.Hour), false},
+		{monday.Add(19*time.Hour + 50*time.Minute), true},
+		{monday.Add(20*time.Hour + 30*time.Minute), true},
+		{monday.Add(21 * time.Hour), false},
+		{monday.AddDate(0, 0, 1).Add(20 * time.Hour), false},
+		{monday.AddDate(0, 0, 7).Add(20*time.Hour + 59*time.Minute), true},
+	}
+
+	for _, c := range cases {
+		if got := w.Contains(c.at, lead); got != c.want {
+			t.Error(c.at, "want", c.want, "got", got)
+		}
+	}
+
+	// the lead crosses midnight
+	w = Window{Weekday: time.Sunday, Hour: 0}
+	saturday := time.Date(2021, 2, 6, 23, 55, 0, 0, time.Local)
+	if !w.Contains(saturday, lead) {
+		t.Error(saturday, "want", true, "got", false)
+	}
+}
@@ -0,0 +1,111 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+// Session is one recorded stream
+type Session struct {
+	Name       string
+	Link       string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// AnnouncedAt is when the stream was expected to begin
+	// zero if the stream was not announced
+	AnnouncedAt time.Time `json:",omitempty"`
+	SaveAs      string
+}
+
+// Duration of the session
+func (s Session) Duration() time.Duration {
+	if s.FinishedAt.Before(s.StartedAt) {
+		return 0
+	}
+	return s.FinishedAt.Sub(s.StartedAt)
+}
+
+// every session we record is appended to this file
+// the config path is read each time so tests can give their own
+func sessionsPath() string {
+	return filepath.Join(options.ConfigPath, "sessions.jsonl")
+}
+
+var m sync.Mutex
+
+// Record a finished session
+func Record(s Session) error {
+	m.Lock()
+	defer m.Unlock()
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("stats.Record: %s", err)
+	}
+
+	f, err := os.OpenFile(sessionsPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("stats.Record: %s", err)
+	}
+	defer f.Close()
+
+	data = append(data, '\n')
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("stats.Record: %s", err)
+	}
+
+	return nil
+}
+
+// Load every recorded session
+func Load() (sessions []Session, err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	f, err := os.Open(sessionsPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stats.Load: %s", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var session Session
+		if err := json.Unmarshal(s.Bytes(), &session); err != nil {
+			// skip a damaged line
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("stats.Load: %s", err)
+	}
+
+	return sessions, nil
+}
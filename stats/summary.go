@@ -0,0 +1,186 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// a stream that starts within this long of its announced time is on time
+const onTimeWithin = 15 * time.Minute
+
+// Summary of the sessions for one target
+type Summary struct {
+	Name    string
+	Link    string
+	Count   int
+	Total   time.Duration
+	Average time.Duration
+	Last    time.Time
+	// most common start hour for each weekday or -1 if they never streamed that day
+	TypicalHour [7]int
+	// number of streams started on each weekday
+	Weekdays [7]int
+	// announced streams and how many started on time
+	Announced int
+	OnTime    int
+	// average of how late announced streams began
+	MeanDelay time.Duration
+}
+
+// Reliability is the fraction of announced streams that started on time
+func (s Summary) Reliability() float64 {
+	if s.Announced == 0 {
+		return 0
+	}
+	return float64(s.OnTime) / float64(s.Announced)
+}
+
+// Summarize sessions for each target sorted by number of streams
+func Summarize(sessions []Session) (summaries []Summary) {
+	byLink := make(map[string][]Session)
+	for _, s := range sessions {
+		byLink[s.Link] = append(byLink[s.Link], s)
+	}
+
+	for _, lst := range byLink {
+		summaries = append(summaries, summarize(lst))
+	}
+
+	sort.Slice(summaries, func(a, b int) bool {
+		if summaries[a].Count == summaries[b].Count {
+			return summaries[a].Link < summaries[b].Link
+		}
+		return summaries[a].Count > summaries[b].Count
+	})
+
+	return
+}
+
+// expects every session to be for the same target
+func summarize(sessions []Session) (s Summary) {
+	var hours [7][24]int
+	var delay time.Duration
+	for _, session := range sessions {
+		// prefer the most recent name
+		if session.StartedAt.After(s.Last) {
+			s.Last = session.StartedAt
+			s.Name = session.Name
+		}
+		s.Link = session.Link
+		s.Count++
+		s.Total += session.Duration()
+
+		at := session.StartedAt.Local()
+		hours[at.Weekday()][at.Hour()]++
+		s.Weekdays[at.Weekday()]++
+
+		if !session.AnnouncedAt.IsZero() {
+			s.Announced++
+			d := session.StartedAt.Sub(session.AnnouncedAt)
+			delay += d
+			if d.Abs() <= onTimeWithin {
+				s.OnTime++
+			}
+		}
+	}
+
+	if s.Count > 0 {
+		s.Average = s.Total / time.Duration(s.Count)
+	}
+	if s.Announced > 0 {
+		s.MeanDelay = delay / time.Duration(s.Announced)
+	}
+
+	for day := range hours {
+		s.TypicalHour[day] = -1
+		most := 0
+		for hour, n := range hours[day] {
+			if n > most {
+				most = n
+				s.TypicalHour[day] = hour
+			}
+		}
+	}
+
+	return
+}
+
+// Find summaries matching a link or part of a name
+func Find(summaries []Summary, query string) (found []Summary) {
+	query = strings.ToLower(query)
+	for _, s := range summaries {
+		name := strings.ToLower(s.Name)
+		if s.Link == query || strings.Contains(name, query) || strings.Contains(strings.ToLower(s.Link), query) {
+			found = append(found, s)
+		}
+	}
+	return
+}
+
+// Output a table of summaries
+func Output(dst io.Writer, summaries []Summary) error {
+	tw := tabwriter.NewWriter(dst, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "Streams\tTotal\tAverage\tOn time\tLast\tName")
+	for _, s := range summaries {
+		onTime := "-"
+		if s.Announced > 0 {
+			onTime = fmt.Sprintf("%d/%d", s.OnTime, s.Announced)
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			s.Count,
+			s.Total.Truncate(time.Minute),
+			s.Average.Truncate(time.Minute),
+			onTime,
+			s.Last.Local().Format("2006-01-02"),
+			s.Name,
+		)
+	}
+
+	return tw.Flush()
+}
+
+// OutputDetail for a single target
+func (s Summary) OutputDetail(dst io.Writer) error {
+	tw := tabwriter.NewWriter(dst, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(tw, "%s\n%s\n\n", s.Name, s.Link)
+	_, _ = fmt.Fprintf(tw, "Streams\t%d\n", s.Count)
+	_, _ = fmt.Fprintf(tw, "Total\t%s\n", s.Total.Truncate(time.Minute))
+	_, _ = fmt.Fprintf(tw, "Average\t%s\n", s.Average.Truncate(time.Minute))
+	_, _ = fmt.Fprintf(tw, "Last\t%s\n", s.Last.Local().Format("2006-01-02 15:04"))
+	if s.Announced > 0 {
+		_, _ = fmt.Fprintf(tw, "On time\t%d of %d announced (%.0f%%)\n", s.OnTime, s.Announced, 100*s.Reliability())
+		_, _ = fmt.Fprintf(tw, "Average delay\t%s\n", s.MeanDelay.Truncate(time.Second))
+	}
+
+	_, _ = fmt.Fprintln(tw, "\nDay\tStreams\tUsual start")
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		hour := "-"
+		if s.TypicalHour[day] >= 0 {
+			hour = fmt.Sprintf("%02d:00", s.TypicalHour[day])
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", day, s.Weekdays[day], hour)
+	}
+
+	return tw.Flush()
+}
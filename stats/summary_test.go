@@ -0,0 +1,86 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	link := "https://www.showroom-live.com/46_KYOKO_SAITO"
+	// two mondays and a tuesday
+	monday := time.Date(2021, 2, 1, 20, 0, 0, 0, time.Local)
+	sessions := []Session{
+		{
+			Name:        "齊 藤 京 子",
+			Link:        link,
+			StartedAt:   monday,
+			FinishedAt:  monday.Add(time.Hour),
+			AnnouncedAt: monday.Add(-5 * time.Minute),
+		},
+		{
+			Name:        "齊 藤 京 子",
+			Link:        link,
+			StartedAt:   monday.AddDate(0, 0, 7).Add(10 * time.Minute),
+			FinishedAt:  monday.AddDate(0, 0, 7).Add(40 * time.Minute),
+			AnnouncedAt: monday.AddDate(0, 0, 7).Add(-time.Hour),
+		},
+		{
+			Name:       "齊 藤 京 子",
+			Link:       link,
+			StartedAt:  monday.AddDate(0, 0, 8).Add(2 * time.Hour),
+			FinishedAt: monday.AddDate(0, 0, 8).Add(3 * time.Hour),
+		},
+		{
+			Name:       "someone else",
+			Link:       "https://www.showroom-live.com/48_Manaka_Taguchi",
+			StartedAt:  monday,
+			FinishedAt: monday.Add(time.Hour),
+		},
+	}
+
+	got := Summarize(sessions)
+	if len(got) != 2 {
+		t.Fatal("want", 2, "got", len(got))
+	}
+
+	s := got[0]
+	if s.Link != link {
+		t.Fatal("want", link, "got", s.Link)
+	}
+	if s.Count != 3 {
+		t.Error("want", 3, "got", s.Count)
+	}
+	if want := 2*time.Hour + 30*time.Minute; s.Total != want {
+		t.Error("want", want, "got", s.Total)
+	}
+	if want := 50 * time.Minute; s.Average != want {
+		t.Error("want", want, "got", s.Average)
+	}
+	if s.TypicalHour[time.Monday] != 20 {
+		t.Error("want", 20, "got", s.TypicalHour[time.Monday])
+	}
+	if s.TypicalHour[time.Tuesday] != 22 {
+		t.Error("want", 22, "got", s.TypicalHour[time.Tuesday])
+	}
+	if s.TypicalHour[time.Sunday] != -1 {
+		t.Error("want", -1, "got", s.TypicalHour[time.Sunday])
+	}
+	if s.Announced != 2 || s.OnTime != 1 {
+		t.Error("want", "1/2", "got", s.OnTime, s.Announced)
+	}
+}
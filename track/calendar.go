@@ -0,0 +1,134 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// how long we guess a stream lasts when we give it to a calendar
+var calendarEventDuration = time.Hour
+
+// Scheduled is a stream we are waiting to snipe
+type Scheduled struct {
+	Name string
+	Link string
+	At   time.Time
+	// when we were told about this time
+	UpdatedAt time.Time
+}
+
+// Schedule gives every upcoming snipe soonest first
+// only the latest time for each target is given
+func Schedule() (scheduled []Scheduled) {
+	sniping.RLock()
+	latest := make(map[string]Scheduled)
+	for task, createdAt := range sniping.tasks {
+		if s, ok := latest[task.link]; ok && !createdAt.After(s.UpdatedAt) {
+			continue
+		}
+		latest[task.link] = Scheduled{
+			Name:      task.name,
+			Link:      task.link,
+			At:        task.at,
+			UpdatedAt: createdAt,
+		}
+	}
+	sniping.RUnlock()
+
+	for _, s := range latest {
+		scheduled = append(scheduled, s)
+	}
+	sort.Slice(scheduled, func(a, b int) bool {
+		return scheduled[a].At.Before(scheduled[b].At)
+	})
+
+	return
+}
+
+// WriteCalendar writes scheduled streams as an iCalendar feed
+// each target keeps the same event so a reschedule updates it in place
+func WriteCalendar(w io.Writer, scheduled []Scheduled) error {
+	const stamp = "20060102T150405Z"
+
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldCalendarLine(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//autosr//schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:autosr")
+	for _, s := range scheduled {
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:%x@autosr", sha1.Sum([]byte(s.Link))))
+		// a later reschedule must have a higher sequence
+		line(fmt.Sprintf("SEQUENCE:%d", s.UpdatedAt.Unix()))
+		line("DTSTAMP:" + s.UpdatedAt.UTC().Format(stamp))
+		line("LAST-MODIFIED:" + s.UpdatedAt.UTC().Format(stamp))
+		line("DTSTART:" + s.At.UTC().Format(stamp))
+		line("DTEND:" + s.At.Add(calendarEventDuration).UTC().Format(stamp))
+		line("SUMMARY:" + escapeCalendarText(s.Name))
+		line("URL:" + s.Link)
+		line("DESCRIPTION:" + escapeCalendarText(s.Link))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var calendarEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func escapeCalendarText(s string) string {
+	return calendarEscaper.Replace(s)
+}
+
+// lines longer than 75 octets are continued on the next line after a space
+// runes are never split
+func foldCalendarLine(s string) string {
+	const limit = 75
+
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+
+	return b.String()
+}
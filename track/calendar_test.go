@@ -0,0 +1,104 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleKeepsLatest(t *testing.T) {
+	now := time.Now()
+	early := snipeTask{name: "a", link: "https://example.com/a", at: now.Add(time.Hour)}
+	moved := snipeTask{name: "a", link: "https://example.com/a", at: now.Add(2 * time.Hour)}
+	other := snipeTask{name: "b", link: "https://example.com/b", at: now.Add(30 * time.Minute)}
+
+	sniping.Lock()
+	sniping.tasks[early] = now.Add(-time.Minute)
+	sniping.tasks[moved] = now
+	sniping.tasks[other] = now
+	sniping.Unlock()
+	defer func() {
+		delSnipeTask(early)
+		delSnipeTask(moved)
+		delSnipeTask(other)
+	}()
+
+	scheduled := Schedule()
+	if len(scheduled) != 2 {
+		t.Fatalf("expected 2 scheduled streams: %v", scheduled)
+	}
+	if scheduled[0].Link != other.link {
+		t.Errorf("expected the soonest first: %v", scheduled)
+	}
+	if !scheduled[1].At.Equal(moved.at) {
+		t.Errorf("expected the rescheduled time: %v", scheduled[1].At)
+	}
+}
+
+func TestWriteCalendar(t *testing.T) {
+	at := time.Date(2021, 1, 2, 11, 0, 0, 0, time.UTC)
+	scheduled := []Scheduled{{
+		Name:      "Someone, live; again",
+		Link:      "https://www.showroom-live.com/someone",
+		At:        at,
+		UpdatedAt: at.Add(-time.Hour),
+	}}
+
+	var b strings.Builder
+	if err := WriteCalendar(&b, scheduled); err != nil {
+		t.Fatal(err)
+	}
+	ics := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"BEGIN:VEVENT\r\n",
+		"DTSTART:20210102T110000Z\r\n",
+		"DTEND:20210102T120000Z\r\n",
+		`SUMMARY:Someone\, live\; again` + "\r\n",
+		"URL:https://www.showroom-live.com/someone\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("expected %q in:\n%s", want, ics)
+		}
+	}
+
+	// the same target keeps its event
+	var again strings.Builder
+	scheduled[0].At = at.Add(time.Hour)
+	if err := WriteCalendar(&again, scheduled); err != nil {
+		t.Fatal(err)
+	}
+	uid := func(s string) string {
+		ndx := strings.Index(s, "UID:")
+		return s[ndx : ndx+strings.Index(s[ndx:], "\r\n")]
+	}
+	if uid(ics) != uid(again.String()) {
+		t.Errorf("expected the same uid after a reschedule")
+	}
+}
+
+func TestFoldCalendarLine(t *testing.T) {
+	s := "SUMMARY:" + strings.Repeat("あ", 40)
+	for _, line := range strings.Split(foldCalendarLine(s), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line is %d octets: %q", len(line), line)
+		}
+	}
+}
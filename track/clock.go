@@ -0,0 +1,113 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes timers for tracking
+// tests replace it so nothing has to wait for real time to pass
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer made by a Clock
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker made by a Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+var clock = struct {
+	sync.RWMutex
+	Clock
+}{
+	Clock: realClock{},
+}
+
+func getClock() Clock {
+	clock.RLock()
+	defer clock.RUnlock()
+	return clock.Clock
+}
+
+// SetClock replaces the clock used for tracking
+// gives a function that puts the previous clock back
+func SetClock(c Clock) (restore func()) {
+	clock.Lock()
+	defer clock.Unlock()
+	prev := clock.Clock
+	clock.Clock = c
+	return func() {
+		clock.Lock()
+		defer clock.Unlock()
+		clock.Clock = prev
+	}
+}
+
+// Now gives the time according to the clock used for tracking
+// modules should use it when they snipe a target that is live now
+func Now() time.Time {
+	return getClock().Now()
+}
+
+func until(t time.Time) time.Duration {
+	return t.Sub(Now())
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
@@ -26,9 +26,22 @@ import (
 
 // DisplayRow of data
 type DisplayRow struct {
+	// ID is stable for as long as the target is tracked
+	// zero for a blank separator line
+	ID     int
 	Status string
 	Name   string
 	Link   string
+	// what the stream is called right now if the target gives one
+	Title string
+	// they are live but watching needs a ticket
+	TicketRequired bool
+}
+
+// Titler is implemented by targets whose streams have a title
+type Titler interface {
+	// empty if there is no title
+	Title() string
 }
 
 // DisplayTable tracking data
@@ -40,6 +53,7 @@ type DisplayTable struct {
 
 func displayRow(t *tracked) (row DisplayRow, err error) {
 	row = DisplayRow{
+		ID:     t.ID(),
 		Status: "unknown",
 		Name:   t.Display(),
 		Link:   t.Link(),
@@ -51,15 +65,20 @@ func displayRow(t *tracked) (row DisplayRow, err error) {
 	}
 
 	if t.IsLive() {
-		d := time.Now().Sub(t.StartedAt()).Truncate(5 * time.Minute)
+		d := Now().Sub(t.StartedAt()).Truncate(5 * time.Minute)
 		if d > time.Second {
 			s := strings.TrimSuffix(d.String(), "0s")
 			row.Status = fmt.Sprintf("Now (%s)", s)
 		} else {
 			row.Status = "Now"
 		}
+		row.Title = t.Title()
+	} else if t.isTicketRequired() {
+		row.Status = "Needs ticket"
+		row.TicketRequired = true
+		row.Title = t.Title()
 	} else if t.IsUpcoming() {
-		at := time.Until(t.UpcomingAt()).Truncate(time.Second)
+		at := until(t.UpcomingAt()).Truncate(time.Second)
 		if at > time.Second {
 			row.Status = fmt.Sprintf("Soon (%s)", at)
 		} else {
@@ -107,7 +126,7 @@ func Display() (d DisplayTable) {
 	var upcoming []*tracked
 	var offline []*tracked
 	for _, t := range tracking {
-		if t.IsLive() {
+		if t.IsLive() || t.isTicketRequired() {
 			live = append(live, t)
 		} else if t.IsUpcoming() {
 			upcoming = append(upcoming, t)
@@ -127,26 +146,34 @@ func Display() (d DisplayTable) {
 	return
 }
 
-// Output for ui
-func (d DisplayTable) Output(dst io.Writer) error {
-	tw := tabwriter.NewWriter(dst, 0, 0, 4, ' ', 0)
-
-	for _, row := range d.Live {
-		_, _ = fmt.Fprintf(tw, "%s\t%s\n", row.Status, row.Name)
-	}
+// Lines gives each row in the order Output writes them
+// blank separator lines are given as an empty row so the index of a row is its line number
+func (d DisplayTable) Lines() (lines []DisplayRow) {
+	lines = append(lines, d.Live...)
 	if len(d.Live) > 0 {
-		_, _ = fmt.Fprintln(tw, "\t\t\t")
+		lines = append(lines, DisplayRow{})
 	}
 
-	for _, row := range d.Upcoming {
-		_, _ = fmt.Fprintf(tw, "%s\t%s\n", row.Status, row.Name)
-	}
+	lines = append(lines, d.Upcoming...)
 	if len(d.Upcoming) > 0 {
-		_, _ = fmt.Fprintln(tw, "\t\t\t")
+		lines = append(lines, DisplayRow{})
 	}
 
-	for _, row := range d.Offline {
-		_, _ = fmt.Fprintf(tw, "%s\t%s\n", row.Status, row.Name)
+	lines = append(lines, d.Offline...)
+
+	return
+}
+
+// Output for ui
+func (d DisplayTable) Output(dst io.Writer) error {
+	tw := tabwriter.NewWriter(dst, 0, 0, 4, ' ', 0)
+
+	for _, row := range d.Lines() {
+		if row.Link == "" {
+			_, _ = fmt.Fprintln(tw, "\t\t\t")
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", row.Status, row.Name, row.Title)
 	}
 
 	return tw.Flush()
@@ -0,0 +1,75 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"context"
+	"time"
+
+	"github.com/bobbytrapz/autosr/stats"
+)
+
+// things only tests need to reach
+
+var ErrSnipeTimeout = errSnipeTimeout
+
+// Track begins tracking a target from a module without asking the module
+func Track(m Module, t Target) (untrack func()) {
+	beginTracking(&tracked{
+		target:   t,
+		cancel:   make(chan struct{}),
+		hostname: m.Hostname(),
+	})
+	return func() {
+		if removed := endTracking(t.Link()); removed != nil {
+			removed.Cancel()
+		}
+	}
+}
+
+func PerformSnipe(ctx context.Context, link string, at time.Time) error {
+	return performSnipe(ctx, getTracking(link), at)
+}
+
+func PerformSave(ctx context.Context, link, streamURL string) error {
+	return performSave(ctx, getTracking(link), streamURL)
+}
+
+func MaybeRecover(ctx context.Context, link string) (time.Duration, string, error) {
+	return maybeRecover(ctx, getTracking(link))
+}
+
+func Poll(ctx context.Context, m Module) error {
+	return poll(ctx, m)
+}
+
+func IsSaving(link string) bool {
+	_, at := findSaveTask(link)
+	return !at.IsZero()
+}
+
+func FinishedAt(link string) time.Time {
+	return getTracking(link).FinishedAt()
+}
+
+// sessions are given to record instead of the stats file
+func SetRecordStats(record func(stats.Session) error) (restore func()) {
+	prev := recordStats
+	recordStats = record
+	return func() {
+		recordStats = prev
+	}
+}
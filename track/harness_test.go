@@ -0,0 +1,450 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/stats"
+	"github.com/bobbytrapz/autosr/track"
+	"github.com/bobbytrapz/autosr/track/tracktest"
+)
+
+// how long we wait in real time for something to happen
+const realTimeout = 5 * time.Second
+
+type harness struct {
+	t      *testing.T
+	ctx    context.Context
+	clock  *tracktest.Clock
+	runner *tracktest.Runner
+	module *tracktest.Module
+
+	mu       sync.Mutex
+	sessions []stats.Session
+}
+
+func newHarness(t *testing.T) *harness {
+	h := &harness{
+		t:      t,
+		clock:  tracktest.NewClock(time.Date(2021, 1, 2, 19, 0, 0, 0, time.UTC)),
+		runner: tracktest.NewRunner(),
+		module: tracktest.NewModule("harness.test"),
+	}
+
+	configPath := options.ConfigPath
+	options.ConfigPath = t.TempDir()
+	options.Set("save_to", t.TempDir())
+	restoreClock := track.SetClock(h.clock)
+	restoreRunner := track.SetRunner(h.runner)
+	restoreStats := track.SetRecordStats(func(s stats.Session) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.sessions = append(h.sessions, s)
+		return nil
+	})
+	t.Cleanup(func() {
+		restoreStats()
+		restoreRunner()
+		restoreClock()
+		options.ConfigPath = configPath
+	})
+
+	// everything the test started must finish before we put things back
+	var cancel context.CancelFunc
+	h.ctx, cancel = context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		track.Wait()
+	})
+
+	return h
+}
+
+// track a new target
+func (h *harness) target(name string) *tracktest.Target {
+	tt := h.module.Add(tracktest.NewTarget(name, "https://harness.test/"+name))
+	h.t.Cleanup(track.Track(h.module, tt))
+	return tt
+}
+
+// moves the clock by step until done gives true
+func (h *harness) advanceUntil(step time.Duration, done func() bool) {
+	h.t.Helper()
+	deadline := time.Now().Add(realTimeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			h.t.Fatal("gave up waiting at", h.clock.Now())
+		}
+		h.clock.Advance(step)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (h *harness) started() *tracktest.Process {
+	h.t.Helper()
+	select {
+	case p := <-h.runner.Started():
+		return p
+	case <-time.After(realTimeout):
+		h.t.Fatal("the downloader did not start")
+	}
+	return nil
+}
+
+func (h *harness) event(tt *tracktest.Target, want string) {
+	h.t.Helper()
+	for {
+		select {
+		case got := <-tt.Events():
+			if got == want {
+				return
+			}
+		case <-time.After(realTimeout):
+			h.t.Fatalf("%s was never called", want)
+		}
+	}
+}
+
+func (h *harness) recorded() []stats.Session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]stats.Session(nil), h.sessions...)
+}
+
+func TestSnipeSavesWhenLive(t *testing.T) {
+	h := newHarness(t)
+	tt := h.target("someone")
+	streamURL := "https://harness.test/someone/index.m3u8"
+
+	at := h.clock.Now().Add(10 * time.Minute)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- track.PerformSnipe(h.ctx, tt.Link(), at)
+	}()
+	h.event(tt, "BeginSnipe")
+
+	// nothing happens until the time they gave us
+	h.clock.BlockUntil(1)
+	tt.SetLive(streamURL)
+	h.clock.Advance(10 * time.Minute)
+
+	p := h.started()
+	if !p.HasArg(streamURL) {
+		t.Errorf("expected the downloader to be given the stream: %v", p.Args)
+	}
+	h.event(tt, "BeginSave")
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	// the stream ends and they do not come back
+	tt.SetOffline()
+	p.Exit(nil)
+	h.advanceUntil(10*time.Second, func() bool {
+		return !track.IsSaving(tt.Link())
+	})
+	h.event(tt, "EndSave")
+
+	sessions := h.recorded()
+	if len(sessions) != 1 {
+		t.Fatalf("expected one session: %v", sessions)
+	}
+	if sessions[0].SaveAs == "" || !sessions[0].AnnouncedAt.Equal(at) {
+		t.Errorf("unexpected session: %+v", sessions[0])
+	}
+}
+
+func TestSnipeTimeout(t *testing.T) {
+	h := newHarness(t)
+	tt := h.target("nobody")
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- track.PerformSnipe(h.ctx, tt.Link(), h.clock.Now())
+	}()
+
+	var err error
+	h.advanceUntil(30*time.Second, func() bool {
+		select {
+		case err = <-errc:
+			return true
+		default:
+			return false
+		}
+	})
+	if !errors.Is(err, track.ErrSnipeTimeout) {
+		t.Errorf("expected a timeout: %v", err)
+	}
+
+	select {
+	case p := <-h.runner.Started():
+		t.Errorf("expected nothing to be saved: %v", p.Args)
+	default:
+	}
+}
+
+func TestSaveRecoversAndStops(t *testing.T) {
+	h := newHarness(t)
+	tt := h.target("flaky")
+	first := "https://harness.test/flaky/1.m3u8"
+	second := "https://harness.test/flaky/2.m3u8"
+
+	tt.SetLive(first)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- track.PerformSave(h.ctx, tt.Link(), first)
+	}()
+	p1 := h.started()
+
+	// the downloader stops but they are still live with a new stream
+	tt.ScriptLive(false)
+	tt.SetLive(second)
+	p1.Exit(errors.New("connection reset"))
+	h.advanceUntil(time.Second, func() bool {
+		return len(h.runner.Started()) > 0
+	})
+	p2 := h.started()
+	if !p2.HasArg(second) {
+		t.Errorf("expected the new stream: %v", p2.Args)
+	}
+
+	if err := track.StopSave(tt.Link()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(realTimeout):
+		t.Fatal("the save did not stop")
+	}
+	if !p2.Killed() {
+		t.Error("expected the downloader to be killed")
+	}
+	if sessions := h.recorded(); len(sessions) != 1 {
+		t.Errorf("expected one session for both downloads: %v", sessions)
+	}
+}
+
+func TestMaybeRecoverGivesUp(t *testing.T) {
+	h := newHarness(t)
+	tt := h.target("gone")
+
+	type result struct {
+		d   time.Duration
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		d, _, err := track.MaybeRecover(h.ctx, tt.Link())
+		done <- result{d, err}
+	}()
+
+	var r result
+	h.advanceUntil(10*time.Second, func() bool {
+		select {
+		case r = <-done:
+			return true
+		default:
+			return false
+		}
+	})
+	if r.err == nil {
+		t.Fatal("expected to give up")
+	}
+	if r.d < 5*time.Minute {
+		t.Errorf("expected to wait for them to come back: %s", r.d)
+	}
+}
+
+func TestPollSnipesLiveTargets(t *testing.T) {
+	h := newHarness(t)
+	options.Set("adaptive_polling", false)
+	tt := h.target("regular")
+
+	if err := track.Poll(h.ctx, h.module); err != nil {
+		t.Fatal(err)
+	}
+
+	// everyone is checked when we start
+	checked := func() bool {
+		for {
+			select {
+			case links := <-h.module.Checked():
+				for _, link := range links {
+					if link == tt.Link() {
+						return true
+					}
+				}
+			default:
+				return false
+			}
+		}
+	}
+	h.advanceUntil(time.Second, checked)
+
+	tt.SetLive("https://harness.test/regular/index.m3u8")
+	h.advanceUntil(time.Second, func() bool {
+		return len(h.runner.Started()) > 0
+	})
+	h.started()
+	h.event(tt, "BeginSave")
+}
+
+func TestPollChecksEveryoneAtStart(t *testing.T) {
+	h := newHarness(t)
+	options.Set("adaptive_polling", false)
+	checkEvery := options.GetDuration("check_every")
+	options.Set("check_every", time.Hour)
+	defer options.Set("check_every", checkEvery)
+
+	// they were already live when we started
+	tt := h.target("early")
+	tt.SetLive("https://harness.test/early/index.m3u8")
+	began := h.clock.Now()
+	if err := track.Poll(h.ctx, h.module); err != nil {
+		t.Fatal(err)
+	}
+
+	h.advanceUntil(time.Second, func() bool {
+		return len(h.runner.Started()) > 0
+	})
+	h.started()
+	if waited := h.clock.Now().Sub(began); waited > time.Minute {
+		t.Errorf("expected to begin saving right away: waited %s", waited)
+	}
+}
+
+func TestStoppedSaveStaysStopped(t *testing.T) {
+	h := newHarness(t)
+	options.Set("adaptive_polling", false)
+	tt := h.target("stopped")
+	first := "https://harness.test/stopped/1.m3u8"
+	second := "https://harness.test/stopped/2.m3u8"
+
+	if err := track.Poll(h.ctx, h.module); err != nil {
+		t.Fatal(err)
+	}
+
+	saves := func(streamURL string) *tracktest.Process {
+		t.Helper()
+		h.advanceUntil(time.Second, func() bool {
+			return len(h.runner.Started()) > 0
+		})
+		p := h.started()
+		if !p.HasArg(streamURL) {
+			t.Errorf("expected %s: %v", streamURL, p.Args)
+		}
+		return p
+	}
+	stop := func(p *tracktest.Process) {
+		t.Helper()
+		if err := track.StopSave(tt.Link()); err != nil {
+			t.Fatal(err)
+		}
+		h.advanceUntil(time.Second, func() bool {
+			return !track.IsSaving(tt.Link())
+		})
+		if !p.Killed() {
+			t.Error("expected the downloader to be killed")
+		}
+	}
+	// several checks go by without a new save
+	staysStopped := func() {
+		t.Helper()
+		for i := 0; i < 3*120; i++ {
+			h.clock.Advance(time.Second)
+			time.Sleep(time.Millisecond)
+		}
+		select {
+		case p := <-h.runner.Started():
+			t.Fatalf("expected the stopped live to be left alone: %v", p.Args)
+		default:
+		}
+	}
+
+	tt.SetLive(first)
+	stop(saves(first))
+	staysStopped()
+
+	// once they go offline their next live is saved
+	tt.SetOffline()
+	staysStopped()
+	tt.SetLive(first)
+	stop(saves(first))
+
+	// so is a new live even if we never saw them offline
+	tt.SetLive(second)
+	saves(second)
+}
+
+func TestTicketRequiredIsShown(t *testing.T) {
+	h := newHarness(t)
+	options.Set("adaptive_polling", false)
+	tt := h.target("premium")
+	tt.SetTicketed(true)
+
+	if err := track.Poll(h.ctx, h.module); err != nil {
+		t.Fatal(err)
+	}
+
+	needsTicket := func() bool {
+		for _, row := range track.Display().Live {
+			if row.Link == tt.Link() {
+				return row.TicketRequired && row.Status == "Needs ticket"
+			}
+		}
+		return false
+	}
+
+	tt.SetLive("https://harness.test/premium/index.m3u8")
+	h.advanceUntil(time.Second, needsTicket)
+
+	// several checks go by without a save
+	for i := 0; i < 3*120; i++ {
+		h.clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case p := <-h.runner.Started():
+		t.Fatalf("expected no save without a ticket: %v", p.Args)
+	default:
+	}
+	if !needsTicket() {
+		t.Error("expected the live to still need a ticket")
+	}
+
+	// once they go offline it is forgotten
+	tt.SetOffline()
+	h.advanceUntil(time.Second, func() bool {
+		return !needsTicket()
+	})
+
+	// and a live we can watch is saved
+	tt.SetTicketed(false)
+	tt.SetLive("https://harness.test/premium/free.m3u8")
+	h.advanceUntil(time.Second, func() bool {
+		return len(h.runner.Started()) > 0
+	})
+	h.started()
+}
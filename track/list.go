@@ -19,6 +19,7 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -29,12 +30,42 @@ import (
 )
 
 // list of urls to watch
-var listPath = filepath.Join(options.ConfigPath, "track.list")
+// the config path is read each time so tests can give their own
+func listPath() string {
+	return filepath.Join(options.ConfigPath, "track.list")
+}
+
+// what each link in the list was normalized to
+// a module may have to ask its site so we only do it once for each link
+var normalized = struct {
+	sync.Mutex
+	links map[string]string
+}{links: make(map[string]string)}
+
+// gives the link we track for a link in the list
+func normalizeListed(ctx context.Context, link string) string {
+	normalized.Lock()
+	normal, ok := normalized.links[link]
+	normalized.Unlock()
+	if ok {
+		return normal
+	}
+
+	normal = normalizeLink(ctx, link)
+	// a link that did not change may be one the site could not tell us about yet
+	if normal != link {
+		normalized.Lock()
+		normalized.links[link] = normal
+		normalized.Unlock()
+	}
+
+	return normal
+}
 
 func readList(ctx context.Context) error {
 	log.Println("track.readList: reading...")
 
-	f, err := os.OpenFile(listPath, os.O_CREATE|os.O_RDONLY, 0600)
+	f, err := os.OpenFile(listPath(), os.O_CREATE|os.O_RDONLY, 0600)
 	if err != nil {
 		return fmt.Errorf("track.readList: %s", err)
 	}
@@ -42,14 +73,27 @@ func readList(ctx context.Context) error {
 
 	// read valid urls from track list
 	s := bufio.NewScanner(f)
-	lst := make(map[string]bool, len(tracking))
+	lst := make(map[string]map[string]string, len(tracking))
+	listed := make(map[string]bool)
 	for s.Scan() {
-		url := strings.TrimSpace(s.Text())
-		if url == "" || url[0] == '#' {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		link, opts := parseListLine(line)
+		normal := normalizeListed(ctx, link)
+		key := linkKey(normal)
+		if listed[key] {
+			log.Println("track.readList: listed more than once:", link)
 			continue
 		}
-		lst[url] = true
+		listed[key] = true
+		if normal != link {
+			log.Println("track.readList:", link, "is", normal)
+		}
+		lst[normal] = opts
 	}
+	setListOptions(lst)
 
 	// remove missing targets
 	for _, t := range tracking {
@@ -100,3 +144,84 @@ func readList(ctx context.Context) error {
 
 	return nil
 }
+
+// ListedLinks gives every link in the track list that is not commented out
+// links are given the way they are tracked
+func ListedLinks(ctx context.Context) (links []string, err error) {
+	data, err := ioutil.ReadFile(listPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("track.ListedLinks: %s", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		link, _ := parseListLine(line)
+		if link == "" || link[0] == '#' {
+			continue
+		}
+		links = append(links, normalizeListed(ctx, link))
+	}
+
+	return
+}
+
+// AppendList adds links to the end of the track list under an optional comment
+// the list is watched so the new targets are added right away
+func AppendList(comment string, links ...string) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(listPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("track.AppendList: %s", err)
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	b.WriteByte('\n')
+	if comment != "" {
+		fmt.Fprintf(&b, "# %s\n", comment)
+	}
+	for _, link := range links {
+		fmt.Fprintln(&b, link)
+	}
+
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("track.AppendList: %s", err)
+	}
+
+	return nil
+}
+
+// UnlistTarget comments out every line in the track list with the given link
+// the list is watched so the target is removed right away
+func UnlistTarget(ctx context.Context, link string) error {
+	data, err := ioutil.ReadFile(listPath())
+	if err != nil {
+		return fmt.Errorf("track.UnlistTarget: %s", err)
+	}
+
+	found := false
+	lines := strings.Split(string(data), "\n")
+	key := linkKey(link)
+	for ndx, line := range lines {
+		if l, _ := parseListLine(line); l == link || (l != "" && l[0] != '#' && linkKey(normalizeListed(ctx, l)) == key) {
+			lines[ndx] = "# " + line
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("track.UnlistTarget: not in track list: %s", link)
+	}
+
+	err = ioutil.WriteFile(listPath(), []byte(strings.Join(lines, "\n")), 0600)
+	if err != nil {
+		return fmt.Errorf("track.UnlistTarget: %s", err)
+	}
+
+	return nil
+}
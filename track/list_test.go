@@ -0,0 +1,86 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+// turns a room number into a link like a site we have to ask would
+type askingModule struct {
+	dummyModule
+	asked int
+}
+
+func (m *askingModule) NormalizeLink(ctx context.Context, link string) (string, error) {
+	if !strings.HasPrefix(link, "https://asking.test/") {
+		return "", errors.New("not a link to a room")
+	}
+	m.asked++
+	return "https://asking.test/room-" + strings.TrimPrefix(link, "https://asking.test/"), nil
+}
+
+func TestListNormalizesOnce(t *testing.T) {
+	m := &askingModule{dummyModule: dummyModule{hostname: "asking.test"}}
+	if err := RegisterModule(m); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		delete(modules, m.hostname)
+		delete(hosts, m.hostname)
+	}()
+
+	normalized.Lock()
+	normalized.links = make(map[string]string)
+	normalized.Unlock()
+
+	configPath := options.ConfigPath
+	options.ConfigPath = t.TempDir()
+	defer func() {
+		options.ConfigPath = configPath
+	}()
+	if err := ioutil.WriteFile(listPath(), []byte("https://asking.test/1\nhttps://asking.test/2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		links, err := ListedLinks(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(links) != 2 || links[0] != "https://asking.test/room-1" {
+			t.Fatalf("unexpected links: %v", links)
+		}
+	}
+	if err := UnlistTarget(ctx, "https://asking.test/room-2"); err != nil {
+		t.Fatal(err)
+	}
+	if m.asked != 2 {
+		t.Errorf("expected each link to be asked about once: %d", m.asked)
+	}
+
+	data, _ := ioutil.ReadFile(listPath())
+	if !strings.Contains(string(data), "# https://asking.test/2") {
+		t.Errorf("expected the link to be commented out:\n%s", data)
+	}
+}
@@ -0,0 +1,71 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Describer is implemented by targets that add details to the save metadata
+type Describer interface {
+	// anything that can be encoded as json
+	Describe() map[string]interface{}
+}
+
+// written next to the first file of a save
+type saveMetadata struct {
+	Name       string
+	Link       string
+	Files      []string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Target     map[string]interface{} `json:",omitempty"`
+}
+
+// SidecarPath gives the path of a file kept next to a recording
+// for example, SidecarPath("a/b.ts", ".json") gives "a/b.json"
+func SidecarPath(saveAs, ext string) string {
+	return strings.TrimSuffix(saveAs, ".ts") + ext
+}
+
+// writes the metadata for a save
+// target details are asked for each time so they stay up to date
+func writeMetadata(t *tracked, meta *saveMetadata) error {
+	if len(meta.Files) == 0 {
+		return nil
+	}
+
+	meta.Target = nil
+	if d, ok := t.target.(Describer); ok {
+		meta.Target = d.Describe()
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("track.writeMetadata: %s", err)
+	}
+
+	p := SidecarPath(meta.Files[0], ".json")
+	if err := ioutil.WriteFile(p, data, 0644); err != nil {
+		return fmt.Errorf("track.writeMetadata: %s", err)
+	}
+
+	return nil
+}
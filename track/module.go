@@ -18,6 +18,11 @@ package track
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
 )
 
 // Module is called by poll and add/remove target
@@ -32,8 +37,44 @@ type Module interface {
 	AddTarget(ctx context.Context, link string) (Target, error)
 }
 
+// LinkNormalizer is implemented by modules that accept more than one kind of link for a target
+// each target is tracked by the link it gives so the same target listed two ways is tracked once
+type LinkNormalizer interface {
+	// gives an error if the module does not accept the link
+	NormalizeLink(ctx context.Context, link string) (string, error)
+}
+
+// Hostnamer is implemented by modules that accept links from more than one hostname
+type Hostnamer interface {
+	// every hostname the module accepts
+	// a name such as "*.example.com" accepts any subdomain of example.com
+	Hostnames() []string
+}
+
+// LinkMatcher is implemented by modules that accept links from hosts they do not name
+// a module that names the link's hostname is always chosen first
+type LinkMatcher interface {
+	MatchLink(link string) bool
+}
+
+// AnyHost is implemented by LinkMatcher modules that take links from any host
+// their Hostname only names the module so it is not listed as a supported site
+type AnyHost interface {
+	AnyHost() bool
+}
+
+// CaseInsensitive is implemented by modules whose links name the same target whatever their case
+// a target listed twice with different case is tracked once
+type CaseInsensitive interface {
+	CaseInsensitive() bool
+}
+
+// modules by the hostname they give
 var modules = make(map[string]Module)
 
+// modules by every hostname they accept
+var hosts = make(map[string]Module)
+
 // RegisterModule with a hostname
 // note: we do not expect to be called after tracking begins
 func RegisterModule(m Module) error {
@@ -43,18 +84,140 @@ func RegisterModule(m Module) error {
 		return errors.New("track.RegisterModule: hostname already registered")
 	}
 
+	var names []string
+	if a, ok := m.(AnyHost); !ok || !a.AnyHost() {
+		names = append(names, hostname)
+	}
+	if h, ok := m.(Hostnamer); ok {
+		names = append(names, h.Hostnames()...)
+	}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if other, ok := hosts[name]; ok && other != m {
+			return fmt.Errorf("track.RegisterModule: %s is already accepted by %s", name, other.Hostname())
+		}
+	}
+
 	modules[hostname] = m
+	for _, name := range names {
+		hosts[strings.ToLower(name)] = m
+	}
 
 	return nil
 }
 
+// gives how well a hostname matches what a module accepts
+// zero if it does not match
+// an exact match beats any wildcard and a longer wildcard beats a shorter one
+func matchHostname(accepts, hostname string) int {
+	if accepts == hostname {
+		return math.MaxInt32
+	}
+	if strings.HasPrefix(accepts, "*.") && strings.HasSuffix(hostname, accepts[1:]) {
+		return len(accepts)
+	}
+
+	return 0
+}
+
+// SupportedSites gives every hostname a module accepts
+func SupportedSites() (sites []string) {
+	for name := range hosts {
+		sites = append(sites, name)
+	}
+	sort.Strings(sites)
+
+	return
+}
+
+// modules are asked in the same order each time
+func moduleNames() (names []string) {
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return
+}
+
+func errUnsupported(what string) error {
+	return fmt.Errorf("%s is not supported (supported sites: %s)", what, strings.Join(SupportedSites(), ", "))
+}
+
 // FindModule with hostname
 func FindModule(hostname string) (m Module, err error) {
-	var ok bool
-	if m, ok = modules[hostname]; ok {
+	hostname = strings.ToLower(hostname)
+
+	best := 0
+	for accepts, mm := range hosts {
+		if score := matchHostname(accepts, hostname); score > best {
+			best = score
+			m = mm
+		}
+	}
+	if m != nil {
 		return
 	}
 
-	err = errors.New("track.FindModule: module not found")
+	err = fmt.Errorf("track.FindModule: %w", errUnsupported(hostname))
 	return
 }
+
+// FindModuleForLink gives the module that tracks a link
+func FindModuleForLink(link string) (m Module, err error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("track.FindModuleForLink: %s", err)
+	}
+
+	if m, err = FindModule(u.Hostname()); err == nil {
+		return
+	}
+
+	for _, name := range moduleNames() {
+		if lm, ok := modules[name].(LinkMatcher); ok && lm.MatchLink(link) {
+			return modules[name], nil
+		}
+	}
+
+	return nil, fmt.Errorf("track.FindModuleForLink: %w", errUnsupported(link))
+}
+
+// gives the same key for two links to the same target
+// only used to find duplicates since the link we track keeps its case
+func linkKey(link string) string {
+	if m, err := FindModuleForLink(link); err == nil {
+		if c, ok := m.(CaseInsensitive); ok && c.CaseInsensitive() {
+			return strings.ToLower(link)
+		}
+	}
+
+	return link
+}
+
+// gives the link a module would like us to use for a target
+// links that no module accepts are given back as they are
+func normalizeLink(ctx context.Context, link string) string {
+	// ask the module for this hostname first
+	if u, err := url.Parse(link); err == nil {
+		if m, err := FindModule(u.Hostname()); err == nil {
+			if n, ok := m.(LinkNormalizer); ok {
+				if normal, err := n.NormalizeLink(ctx, link); err == nil {
+					return normal
+				}
+			}
+			return link
+		}
+	}
+
+	// maybe another module knows what to do with it
+	for _, name := range moduleNames() {
+		if n, ok := modules[name].(LinkNormalizer); ok {
+			if normal, err := n.NormalizeLink(ctx, link); err == nil {
+				return normal
+			}
+		}
+	}
+
+	return link
+}
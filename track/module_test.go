@@ -0,0 +1,139 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type dummyModule struct {
+	hostname  string
+	hostnames []string
+	match     string
+	anyHost   bool
+	anyCase   bool
+}
+
+func (m *dummyModule) Hostname() string {
+	return m.hostname
+}
+
+func (m *dummyModule) Hostnames() []string {
+	return m.hostnames
+}
+
+func (m *dummyModule) MatchLink(link string) bool {
+	return m.match != "" && strings.Contains(link, m.match)
+}
+
+func (m *dummyModule) AnyHost() bool {
+	return m.anyHost
+}
+
+func (m *dummyModule) CaseInsensitive() bool {
+	return m.anyCase
+}
+
+func (m *dummyModule) CheckUpcoming(context.Context, []Target) error {
+	return nil
+}
+
+func (m *dummyModule) AddTarget(ctx context.Context, link string) (Target, error) {
+	return dummy{link: link}, nil
+}
+
+func TestFindModuleForLink(t *testing.T) {
+	site := &dummyModule{hostname: "www.site.test", hostnames: []string{"site.test", "*.site.test"}}
+	live := &dummyModule{hostname: "live.site.test"}
+	byLink := &dummyModule{hostname: "any.test-module", match: ".m3u8", anyHost: true}
+	for _, m := range []*dummyModule{site, live, byLink} {
+		if err := RegisterModule(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for _, m := range []*dummyModule{site, live, byLink} {
+			delete(modules, m.hostname)
+			for _, name := range append([]string{m.hostname}, m.hostnames...) {
+				delete(hosts, name)
+			}
+		}
+	}()
+
+	cases := map[string]Module{
+		"https://www.site.test/ROOM":          site,
+		"https://site.test/ROOM":              site,
+		"https://m.site.test/ROOM":            site,
+		"https://WWW.SITE.TEST/ROOM":          site,
+		"https://live.site.test/ROOM":         live,
+		"https://cdn.example.test/index.m3u8": byLink,
+	}
+	for link, want := range cases {
+		m, err := FindModuleForLink(link)
+		if err != nil {
+			t.Errorf("%s: %s", link, err)
+			continue
+		}
+		if m != want {
+			t.Errorf("%s: got %s; want %s", link, m.Hostname(), want.Hostname())
+		}
+	}
+
+	_, err := FindModuleForLink("https://example.test/ROOM")
+	if err == nil {
+		t.Fatal("expected an unsupported link to give an error")
+	}
+	if !strings.Contains(err.Error(), "*.site.test") {
+		t.Errorf("expected the supported sites to be listed: %s", err)
+	}
+
+	// a module for any host is only found by its links
+	if strings.Contains(err.Error(), "any.test-module") {
+		t.Errorf("expected a module for any host to not be listed: %s", err)
+	}
+	if _, err := FindModule("any.test-module"); err == nil {
+		t.Error("expected a module for any host to not be found by its name")
+	}
+
+	if err := RegisterModule(&dummyModule{hostname: "other.test", hostnames: []string{"site.test"}}); err == nil {
+		t.Error("expected a hostname to be accepted by one module")
+	}
+}
+
+func TestLinkKey(t *testing.T) {
+	anyCase := &dummyModule{hostname: "any-case.test", anyCase: true}
+	exact := &dummyModule{hostname: "exact.test"}
+	for _, m := range []*dummyModule{anyCase, exact} {
+		if err := RegisterModule(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for _, m := range []*dummyModule{anyCase, exact} {
+			delete(modules, m.hostname)
+			delete(hosts, m.hostname)
+		}
+	}()
+
+	if linkKey("https://any-case.test/ROOM") != linkKey("https://any-case.test/room") {
+		t.Error("expected links that differ in case to be the same target")
+	}
+	if linkKey("https://exact.test/ROOM") == linkKey("https://exact.test/room") {
+		t.Error("expected links that differ in case to be different targets")
+	}
+}
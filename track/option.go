@@ -0,0 +1,106 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+// options given after a link in the track list
+// for example,
+// https://www.showroom-live.com/ROOM check_every=10m
+var listOptions = struct {
+	sync.RWMutex
+	opts map[string]map[string]string
+}{
+	opts: make(map[string]map[string]string),
+}
+
+// split a track list line into its link and options
+func parseListLine(line string) (link string, opts map[string]string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+
+	link = fields[0]
+	for _, f := range fields[1:] {
+		sp := strings.SplitN(f, "=", 2)
+		if len(sp) != 2 || sp[0] == "" {
+			// ignore anything that is not an option
+			continue
+		}
+		if opts == nil {
+			opts = make(map[string]string)
+		}
+		opts[sp[0]] = sp[1]
+	}
+
+	return
+}
+
+func setListOptions(lst map[string]map[string]string) {
+	listOptions.Lock()
+	defer listOptions.Unlock()
+	listOptions.opts = lst
+}
+
+// gives an option only if it was set for this target
+func targetOption(link, key string) (value string, ok bool) {
+	listOptions.RLock()
+	defer listOptions.RUnlock()
+	value, ok = listOptions.opts[link][key]
+	return
+}
+
+// Option for a target
+// an option given in the track list overrides the option from the config file
+func Option(link, key string) string {
+	if value, ok := targetOption(link, key); ok {
+		return value
+	}
+
+	return options.Get(key)
+}
+
+// OptionDuration for a target
+func OptionDuration(link, key string) time.Duration {
+	if value, ok := targetOption(link, key); ok {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+
+	return options.GetDuration(key)
+}
+
+// OptionBool for a target
+func OptionBool(link, key string) bool {
+	if value, ok := targetOption(link, key); ok {
+		switch strings.ToLower(value) {
+		case "1", "true", "yes", "on":
+			return true
+		default:
+			return false
+		}
+	}
+
+	return options.GetBool(key)
+}
@@ -17,7 +17,10 @@ package track
 
 import (
 	"context"
+	"errors"
+	"hash/fnv"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/bobbytrapz/autosr/backoff"
@@ -27,11 +30,48 @@ import (
 
 const retryAttempts = 3
 
-var check = make(chan struct{}, 1)
+// how often the scheduler looks for targets that are due to be checked
+var scheduleResolution = 1 * time.Second
+
+// a target is never checked more often than this unless the user asks
+const minCheckInterval = 30 * time.Second
+
+// each module polls on its own so each has a way to be told to check now
+var checks = struct {
+	sync.Mutex
+	chans []chan struct{}
+}{}
+
+func newCheck() chan struct{} {
+	checks.Lock()
+	defer checks.Unlock()
+	check := make(chan struct{}, 1)
+	checks.chans = append(checks.chans, check)
+	return check
+}
+
+func dropCheck(check chan struct{}) {
+	checks.Lock()
+	defer checks.Unlock()
+	for ndx, c := range checks.chans {
+		if c == check {
+			checks.chans = append(checks.chans[:ndx], checks.chans[ndx+1:]...)
+			return
+		}
+	}
+}
 
 // CheckNow makes poll process right now
 func CheckNow() {
-	check <- struct{}{}
+	checks.Lock()
+	defer checks.Unlock()
+	for _, check := range checks.chans {
+		select {
+		case check <- struct{}{}:
+		default:
+			// a check is already waiting
+		}
+	}
 }
 
 func beginPoll(ctx context.Context) error {
@@ -45,92 +85,201 @@ func beginPoll(ctx context.Context) error {
 	return nil
 }
 
+// gives how long to wait between checks for a target
+func checkInterval(link string, now time.Time) time.Duration {
+	base := options.GetDuration("check_every")
+
+	// the user decided for us
+	if _, ok := targetOption(link, "check_every"); ok {
+		d := OptionDuration(link, "check_every")
+		if d < minCheckInterval {
+			d = minCheckInterval
+		}
+		return d
+	}
+
+	// we expect them to stream soon
+	if p := options.GetDuration("prewarm_every"); p > 0 && learned.expected(link, now, options.GetDuration("prewarm_lead")) {
+		return p
+	}
+
+	if !options.GetBool("adaptive_polling") {
+		return base
+	}
+
+	// check rooms that rarely stream less often
+	last := learned.lastSeen(link)
+	switch {
+	case last.IsZero():
+		// we know nothing about them yet
+		return base
+	case now.Sub(last) > 30*24*time.Hour:
+		return 4 * base
+	case now.Sub(last) > 7*24*time.Hour:
+		return 2 * base
+	}
+
+	return base
+}
+
+// spreads first checks across the interval so we do not check every target at once
+func firstCheckAt(link string, now time.Time, interval time.Duration) time.Time {
+	h := fnv.New32a()
+	h.Write([]byte(link))
+	frac := float64(h.Sum32()) / float64(1<<32)
+	return now.Add(time.Duration(frac * float64(interval)))
+}
+
+// gives targets for a module that are due to be checked and schedules their next check
+// targets the user stopped saving or that need a ticket are given separately so we only ask if they are still live
+func dueTargets(hostname string, now time.Time) (targets []Target, stopped []*tracked) {
+	// reading the stats can be slow so we do not hold the lock for it
+	learned.reload(now)
+
+	rw.RLock()
+	defer rw.RUnlock()
+
+	for link, t := range tracking {
+		if t.Hostname() != hostname {
+			continue
+		}
+		if hasSaveTask(saveTask{t.Name(), link}) {
+			continue
+		}
+
+		interval := checkInterval(link, now)
+		next := t.NextCheckAt()
+		if next.IsZero() {
+			t.SetNextCheckAt(firstCheckAt(link, now, interval))
+			continue
+		}
+
+		// a shorter interval may mean we should check sooner than planned
+		if next.After(now.Add(interval)) {
+			next = now.Add(interval)
+			t.SetNextCheckAt(next)
+		}
+
+		if !now.Before(next) {
+			if t.isStopped() || t.isTicketRequired() {
+				stopped = append(stopped, t)
+			} else {
+				targets = append(targets, t.target)
+			}
+			t.SetNextCheckAt(now.Add(interval))
+		}
+	}
+
+	return
+}
+
+// gives every target for a module that is not being saved
+// and schedules their next check
+// spread gives each target its own slot in the interval for the next check
+func allTargets(hostname string, now time.Time, spread bool) (targets []Target, stopped []*tracked) {
+	rw.RLock()
+	defer rw.RUnlock()
+
+	for link, t := range tracking {
+		if t.Hostname() != hostname {
+			continue
+		}
+		if hasSaveTask(saveTask{t.Name(), link}) {
+			continue
+		}
+		if t.isStopped() || t.isTicketRequired() {
+			stopped = append(stopped, t)
+		} else {
+			targets = append(targets, t.target)
+		}
+		interval := checkInterval(link, now)
+		if spread {
+			t.SetNextCheckAt(firstCheckAt(link, now, interval))
+		} else {
+			t.SetNextCheckAt(now.Add(interval))
+		}
+	}
+
+	return
+}
+
 // allows a module to monitor a website
+// each target is checked on its own schedule
 func poll(ctx context.Context, module Module) error {
 	hostname := module.Hostname()
+	check := newCheck()
 
-	// helps make the logic below easier to follow
-	// didForce is true if the user requested this attempt
-	attempt := func(didForce bool) {
-		// gather targets
-		var targets []Target
-		for _, t := range tracking {
-			if t.Hostname() == hostname {
-				name := t.Name()
-				link := t.Link()
-				if hasSaveTask(saveTask{name, link}) {
-					continue
-				}
-				targets = append(targets, t.target)
-				if didForce {
-					go func(tup Target) {
-						tup.Reload(ctx)
-					}(t.target)
+	checkTargets := func(targets []Target) {
+		// retry if possible
+		_, _ = retry.Do(ctx, retry.Options{
+			MaxAttempts: retryAttempts,
+			Policy:      backoff.For(hostname, backoff.Upcoming),
+			After:       getClock().After,
+			OnAttempt: func(n int, err error) {
+				log.Println("track.poll:", hostname, err)
+			},
+		}, func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, module.CheckUpcoming(ctx, targets)
+		})
+	}
+
+	// a stopped live or one that needs a ticket is left alone until they go offline or begin a new live
+	checkStopped := func(stopped []*tracked) {
+		for _, t := range stopped {
+			wg.Add(1)
+			go func(t *tracked) {
+				defer wg.Done()
+				isLive, err := t.CheckLive(ctx)
+				if !isLive && (err == nil || errors.Is(err, ErrNotLive)) {
+					t.seenOffline()
 				}
-			}
+			}(t)
 		}
+	}
+
+	// check everyone right now
+	// didForce is true if the user asked us to
+	sweep := func(didForce bool) {
+		targets, stopped := allTargets(hostname, Now(), !didForce)
+		checkStopped(stopped)
 		if didForce {
-			runHooks("reload", nil)
-		}
-		err := module.CheckUpcoming(ctx, targets)
-		if err != nil {
-			// retry if possible
-			e, ok := retry.Check(err)
-			numAttempts := 0
-			for ; ok; e, ok = retry.Check(err) {
-				select {
-				case <-ctx.Done():
-					log.Println("track.poll:", hostname, ctx.Err())
-					return
-				case <-time.After(backoff.DefaultPolicy.Duration(numAttempts)):
-					numAttempts++
-					err = e.Retry()
-					if err == nil {
-						break
-					}
-					log.Println("track.poll:", hostname, err)
-				}
+			for _, t := range targets {
+				go func(tup Target) {
+					tup.Reload(ctx)
+				}(t)
 			}
+			runHooks("reload", nil)
 		}
+		log.Println("track.poll:", hostname, "check now", len(targets), "targets")
+		checkTargets(targets)
 	}
 
-	// make first attempt right away
-	log.Println("track.poll:", hostname, "first attempt...")
-	attempt(false)
-
 	// poll
 	go func() {
-		defer close(check)
+		defer dropCheck(check)
+
+		// make first attempt right away
+		// then checks are spread across the interval
+		log.Println("track.poll:", hostname, "first attempt...")
+		sweep(false)
 
-		pollRate := options.GetDuration("check_every")
-		log.Println("track.poll:", hostname, pollRate)
-		tick := time.NewTicker(pollRate)
+		log.Println("track.poll:", hostname, options.GetDuration("check_every"))
+		tick := getClock().NewTicker(scheduleResolution)
 		defer tick.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				log.Println("track.poll:", hostname, ctx.Err())
 				return
-			case <-tick.C:
-				attempt(false)
-				// check if poll rate was adjusted
-				p := options.GetDuration("check_every")
-				if p != pollRate {
-					pollRate = p
-					tick.Stop()
-					tick = time.NewTicker(pollRate)
-					log.Println("track.poll:", hostname, "new poll rate", pollRate)
+			case now := <-tick.C():
+				targets, stopped := dueTargets(hostname, now)
+				checkStopped(stopped)
+				if len(targets) > 0 {
+					log.Println("track.poll:", hostname, len(targets), "targets due")
+					checkTargets(targets)
 				}
 			case <-check:
-				attempt(true)
-				// check if poll rate was adjusted
-				p := options.GetDuration("check_every")
-				if p != pollRate {
-					pollRate = p
-					tick.Stop()
-					tick = time.NewTicker(pollRate)
-					log.Println("track.poll:", hostname, "new poll rate", pollRate)
-				}
+				sweep(true)
 			}
 		}
 	}()
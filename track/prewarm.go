@@ -0,0 +1,90 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/stats"
+)
+
+// how often we learn from new sessions
+var prewarmReload = 1 * time.Hour
+
+// what we have learned from recorded sessions
+// many targets begin streaming around the same time each week
+// so we check them more often around the times they usually begin
+var learned = activity{}
+
+type activity struct {
+	sync.RWMutex
+	loadedAt time.Time
+	windows  map[string][]stats.Window
+	last     map[string]time.Time
+}
+
+// learn from sessions if we have not for a while
+func (a *activity) reload(now time.Time) {
+	a.Lock()
+	if now.Sub(a.loadedAt) < prewarmReload {
+		a.Unlock()
+		return
+	}
+	a.loadedAt = now
+	a.Unlock()
+
+	sessions, err := stats.Load()
+	if err != nil {
+		log.Println("track.activity.reload:", err)
+		return
+	}
+
+	windows := stats.Predict(sessions)
+	last := make(map[string]time.Time)
+	for _, s := range sessions {
+		if s.StartedAt.After(last[s.Link]) {
+			last[s.Link] = s.StartedAt
+		}
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	a.windows = windows
+	a.last = last
+}
+
+// true if we expect the target to begin streaming soon
+func (a *activity) expected(link string, now time.Time, lead time.Duration) bool {
+	a.RLock()
+	defer a.RUnlock()
+
+	for _, w := range a.windows[link] {
+		if w.Contains(now, lead) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// when the target last began streaming
+func (a *activity) lastSeen(link string) time.Time {
+	a.RLock()
+	defer a.RUnlock()
+	return a.last[link]
+}
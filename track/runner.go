@@ -0,0 +1,90 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+)
+
+// Runner starts the downloader that saves a stream
+// tests replace it so no real program is run
+type Runner interface {
+	Start(ctx context.Context, app string, args ...string) (Process, error)
+}
+
+// Process is a running downloader
+type Process interface {
+	Pid() int
+	// Wait is only called once
+	Wait() error
+	Kill() error
+}
+
+var runner = struct {
+	sync.RWMutex
+	Runner
+}{
+	Runner: execRunner{},
+}
+
+func getRunner() Runner {
+	runner.RLock()
+	defer runner.RUnlock()
+	return runner.Runner
+}
+
+// SetRunner replaces how downloaders are run
+// gives a function that puts the previous runner back
+func SetRunner(r Runner) (restore func()) {
+	runner.Lock()
+	defer runner.Unlock()
+	prev := runner.Runner
+	runner.Runner = r
+	return func() {
+		runner.Lock()
+		defer runner.Unlock()
+		runner.Runner = prev
+	}
+}
+
+type execRunner struct{}
+
+func (execRunner) Start(ctx context.Context, app string, args ...string) (Process, error) {
+	cmd := exec.CommandContext(ctx, app, args...)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return execProcess{cmd}, nil
+}
+
+type execProcess struct {
+	cmd *exec.Cmd
+}
+
+func (p execProcess) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+func (p execProcess) Wait() error {
+	return p.cmd.Wait()
+}
+
+func (p execProcess) Kill() error {
+	return p.cmd.Process.Kill()
+}
@@ -21,20 +21,38 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/bobbytrapz/autosr/backoff"
 	"github.com/bobbytrapz/autosr/options"
+	"github.com/bobbytrapz/autosr/stats"
 )
 
 // when a stream appears to end we wait to see if the user comes back
 // online just in case there was a problem with the stream
 var recoverTimeout = 5 * time.Minute
 
+// where finished sessions are kept
+// tests replace it so they do not add to the user's stats
+var recordStats = stats.Record
+
+type savePathKey struct{}
+
+func withSavePath(ctx context.Context, saveAs string) context.Context {
+	return context.WithValue(ctx, savePathKey{}, saveAs)
+}
+
+// SavePathFromContext gives the path of the first file saved for a stream
+// the context given to Target.BeginSave and Target.EndSave has the path
+func SavePathFromContext(ctx context.Context) (saveAs string, ok bool) {
+	saveAs, ok = ctx.Value(savePathKey{}).(string)
+	return
+}
+
 type saveTask struct {
 	name string
 	link string
@@ -79,7 +97,7 @@ func addSaveTask(task saveTask) bool {
 	}
 	saving.Lock()
 	defer saving.Unlock()
-	saving.tasks[task] = time.Now()
+	saving.tasks[task] = Now()
 	return true
 }
 
@@ -113,18 +131,32 @@ func performSave(ctx context.Context, t *tracked, streamURL string) error {
 		log.Println("track.save: already saving", task.name)
 		return nil
 	}
+	stop := t.beginSave()
+	changed := t.metadataChanged()
+	var firstSaveAs string
+	meta := saveMetadata{
+		Name: task.name,
+		Link: task.link,
+	}
 	defer func() {
+		meta.FinishedAt = t.FinishedAt()
+		if err := writeMetadata(t, &meta); err != nil {
+			log.Println("track.save:", err)
+		}
+		recordSession(t, firstSaveAs)
+		t.endSave()
 		delSaveTask(task)
-		t.EndSave(ctx)
+		if firstSaveAs != "" {
+			t.EndSave(withSavePath(ctx, firstSaveAs))
+		}
 	}()
-	t.BeginSave(ctx)
 	log.Println("track.save:", task.name)
 
 	// used by command monitor to indicate that the command has exited
 	exit := make(chan error, 1)
 
 	// command information set in the closure below
-	var cmd *exec.Cmd
+	var proc Process
 	var app string
 	var pid int
 	var saveAs string
@@ -132,16 +164,19 @@ func performSave(ctx context.Context, t *tracked, streamURL string) error {
 	// will be called again if we manage to recover a stream
 	runSave := func(url string) error {
 		var err error
-		cmd, saveAs, err = runDownloader(ctx, url, name)
+		var args []string
+		app, args, saveAs, err = runDownloader(url, name, t.Cookies(url))
 		if err != nil {
 			return fmt.Errorf("runSave: %w", err)
 		}
 
-		if err := cmd.Start(); err != nil {
+		proc, err = getRunner().Start(ctx, app, args...)
+		if err != nil {
 			return fmt.Errorf("runSave: %w", err)
 		}
-		app = cmd.Args[0]
-		pid = cmd.Process.Pid
+		pid = proc.Pid()
+		t.setSavingAs(saveAs)
+		meta.Files = append(meta.Files, saveAs)
 		log.Printf("runSave: %s [%s %d]", name, app, pid)
 		runHooks("begin-save", map[string]interface{}{
 			"Name":   task.name,
@@ -150,15 +185,15 @@ func performSave(ctx context.Context, t *tracked, streamURL string) error {
 		})
 
 		// monitor downloader
-		go func() {
-			err := cmd.Wait()
+		go func(proc Process, saveAs string) {
+			err := proc.Wait()
 			runHooks("end-save", map[string]interface{}{
 				"Name":  task.name,
 				"Link":  task.link,
 				"Saved": saveAs,
 			})
 			exit <- err
-		}()
+		}(proc, saveAs)
 
 		return nil
 	}
@@ -167,30 +202,50 @@ func performSave(ctx context.Context, t *tracked, streamURL string) error {
 	if err := runSave(streamURL); err != nil {
 		return err
 	}
+	firstSaveAs = saveAs
+
+	// the module is told where we save once the downloader has started
+	t.BeginSave(withSavePath(ctx, firstSaveAs))
+	meta.StartedAt = t.StartedAt()
+	if err := writeMetadata(t, &meta); err != nil {
+		log.Println("track.save:", err)
+	}
 
 	// handle closing downloader
 	for {
 		select {
 		case <-ctx.Done():
-			_ = cmd.Process.Kill()
-			err := cmd.Wait()
-			t.SetFinishedAt(time.Now())
+			_ = proc.Kill()
+			err := <-exit
+			t.SetFinishedAt(Now())
 			log.Printf("track.save: %s %s [%s %d] (%s)", name, ctx.Err(), app, pid, err)
 			return nil
 		case <-t.cancel:
 			// we have been selected for cancellation
-			_ = cmd.Process.Kill()
-			err := cmd.Wait()
-			t.SetFinishedAt(time.Now())
+			_ = proc.Kill()
+			err := <-exit
+			t.SetFinishedAt(Now())
 			log.Printf("track.save: %s canceled [%s %d] (%s)", name, app, pid, err)
 			return nil
+		case <-stop:
+			// the user asked us to stop this save
+			_ = proc.Kill()
+			err := <-exit
+			t.SetFinishedAt(Now())
+			log.Printf("track.save: %s stopped [%s %d] (%s)", name, app, pid, err)
+			return nil
+		case <-changed:
+			// the target has new details for us
+			if err := writeMetadata(t, &meta); err != nil {
+				log.Println("track.save:", err)
+			}
 		case err := <-exit:
 			// something may have gone wrong so try to recover
 			log.Printf("track.save: %s exited [%s %d]", name, app, pid)
 			d, newURL, err := maybeRecover(ctx, t)
 			if err != nil {
 				// we did not recover so end this save
-				t.SetFinishedAt(time.Now().Add(-d))
+				t.SetFinishedAt(Now().Add(-d))
 				return nil
 			}
 			log.Printf("track.save: %s recovered (%s)", name, d.Truncate(time.Millisecond))
@@ -198,17 +253,49 @@ func performSave(ctx context.Context, t *tracked, streamURL string) error {
 			err = runSave(newURL)
 			if err != nil {
 				log.Printf("track.save: while recovering: %s", err)
-				t.SetFinishedAt(time.Now().Add(-d))
+				t.SetFinishedAt(Now().Add(-d))
 				return nil
 			}
+			if err := writeMetadata(t, &meta); err != nil {
+				log.Println("track.save:", err)
+			}
 		}
 	}
 }
 
+// keep a history of saves for stats
+func recordSession(t *tracked, saveAs string) {
+	if saveAs == "" {
+		// we never started saving
+		return
+	}
+
+	session := stats.Session{
+		Name:       t.Name(),
+		Link:       t.Link(),
+		StartedAt:  t.StartedAt(),
+		FinishedAt: t.FinishedAt(),
+		SaveAs:     saveAs,
+	}
+
+	// only trust an announcement made for around this time
+	if at := t.AnnouncedAt(); !at.IsZero() {
+		if d := session.StartedAt.Sub(at); d.Abs() < 6*time.Hour {
+			session.AnnouncedAt = at
+		}
+		t.SetAnnouncedAt(time.Time{})
+	}
+
+	if err := recordStats(session); err != nil {
+		log.Println("track.recordSession:", err)
+	}
+}
+
 type downloaderArgs struct {
 	UserAgent string
 	SavePath  string
 	StreamURL string
+	Cookies   string
 }
 
 // resembles go templates
@@ -232,6 +319,9 @@ func (dargs downloaderArgs) ReplaceIn(command string) (app string, args []string
 			case "StreamURL":
 				arg := strings.Replace(pat, m[0], dargs.StreamURL, 1)
 				args = append(args, arg)
+			case "Cookies":
+				arg := strings.Replace(pat, m[0], dargs.Cookies, 1)
+				args = append(args, arg)
 			default:
 				args = append(args, pat)
 			}
@@ -244,8 +334,9 @@ func (dargs downloaderArgs) ReplaceIn(command string) (app string, args []string
 	return
 }
 
-// runs the user's downloader
-func runDownloader(ctx context.Context, streamURL, name string) (cmd *exec.Cmd, saveAs string, err error) {
+// prepares the user's downloader
+// cookies is given to the downloader as {{Cookies}}
+func runDownloader(streamURL, name, cookies string) (app string, args []string, saveAs string, err error) {
 	// keep the path safe
 	r := strings.NewReplacer(
 		// linux
@@ -269,7 +360,7 @@ func runDownloader(ctx context.Context, streamURL, name string) (cmd *exec.Cmd,
 	saveTo := filepath.Join(options.Get("save_to"), name)
 	ua := options.Get("user_agent")
 
-	fn := fmt.Sprintf("%s-%s", time.Now().Format("2006-01-02"), name)
+	fn := fmt.Sprintf("%s-%s", Now().Format("2006-01-02"), name)
 	saveAs = fn
 	for n := 2; ; n++ {
 		p := filepath.Join(saveTo, saveAs+".ts")
@@ -286,10 +377,16 @@ func runDownloader(ctx context.Context, streamURL, name string) (cmd *exec.Cmd,
 		UserAgent: ua,
 		SavePath:  saveAs,
 		StreamURL: streamURL,
+		Cookies:   cookies,
+	}
+	app, args = dargs.ReplaceIn(command)
+
+	// cookies are a login so they stay out of the log
+	if dargs.Cookies != "" {
+		dargs.Cookies = "[redacted]"
 	}
-	app, args := dargs.ReplaceIn(command)
-	log.Printf("track.runDownloader: %s %s (%d)\n", app, args, len(args))
-	cmd = exec.CommandContext(ctx, app, args...)
+	_, logArgs := dargs.ReplaceIn(command)
+	log.Printf("track.runDownloader: %s %s (%d)\n", app, logArgs, len(logArgs))
 
 	err = os.MkdirAll(saveTo, os.ModePerm)
 	if err != nil {
@@ -297,20 +394,20 @@ func runDownloader(ctx context.Context, streamURL, name string) (cmd *exec.Cmd,
 		return
 	}
 
-	return cmd, saveAs, nil
+	return app, args, saveAs, nil
 }
 
 func maybeRecover(ctx context.Context, t *tracked) (duration time.Duration, streamURL string, err error) {
-	beginAt := time.Now()
+	beginAt := Now()
 	defer func() {
-		endAt := time.Now()
+		endAt := Now()
 		duration = endAt.Sub(beginAt)
 	}()
 
 	name := t.Name()
 	log.Println("track.maybeRecover:", name, "recovering")
 
-	err = waitForLive(ctx, t, recoverTimeout)
+	err = waitForLive(ctx, t, recoverTimeout, backoff.Recover)
 	if err != nil {
 		log.Println("track.maybeRecover:", name, "is not online")
 		err = errors.New("track.maybeRecover: target is not live")
@@ -318,7 +415,7 @@ func maybeRecover(ctx context.Context, t *tracked) (duration time.Duration, stre
 	}
 	log.Println("track.maybeRecover:", name, "is online")
 
-	streamURL, err = waitForStream(ctx, t, recoverTimeout)
+	streamURL, err = waitForStream(ctx, t, recoverTimeout, backoff.Recover)
 	if err != nil {
 		// we failed to find the new url
 		log.Println("track.maybeRecover:", name, "did not find url")
@@ -0,0 +1,48 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package track
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bobbytrapz/autosr/options"
+)
+
+func TestRunDownloaderHidesCookies(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	command := options.Get("download_with")
+	defer options.Set("download_with", command)
+	options.Set("download_with", "streamlink --http-header Cookie={{Cookies}} -o {{SavePath}} {{StreamURL}} best")
+	options.Set("save_to", t.TempDir())
+
+	_, args, _, err := runDownloader("https://example.com/index.m3u8", "someone", "sr_id=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(args, " "), "Cookie=sr_id=secret") {
+		t.Errorf("expected the downloader to be given the cookies: %v", args)
+	}
+	if strings.Contains(logged.String(), "secret") {
+		t.Errorf("expected the cookies to stay out of the log: %s", logged.String())
+	}
+}
@@ -18,6 +18,7 @@ package track
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -75,7 +76,7 @@ func addSnipeTask(task snipeTask) bool {
 	}
 	sniping.Lock()
 	defer sniping.Unlock()
-	sniping.tasks[task] = time.Now()
+	sniping.tasks[task] = Now()
 	return true
 }
 
@@ -95,10 +96,33 @@ func SnipeTargetAt(ctx context.Context, t Target, at time.Time) error {
 	if tracked == nil {
 		return errors.New("track.SnipeTarget: invalid target")
 	}
+	if tracked.isStopped() {
+		log.Println("track.SnipeTargetAt:", tracked.Name(), "was stopped by the user so we will not snipe")
+		return nil
+	}
+	if tracked.isTicketRequired() {
+		log.Println("track.SnipeTargetAt:", tracked.Name(), "needs a ticket so we will not snipe")
+		return nil
+	}
 
 	return snipeAt(ctx, tracked, at)
 }
 
+// TicketRequired tells us a target is live but watching needs a ticket
+// we show it and leave the live alone until they go offline or begin a new live
+func TicketRequired(t Target) error {
+	tracked := getTracking(t.Link())
+	if tracked == nil {
+		return errors.New("track.TicketRequired: invalid target")
+	}
+	if !tracked.isTicketRequired() {
+		log.Println("track.TicketRequired:", tracked.Name(), "is live but a ticket is required")
+	}
+	tracked.setTicketRequired()
+
+	return nil
+}
+
 // snipes a target at the given time
 func snipeAt(ctx context.Context, tracked *tracked, at time.Time) error {
 	if at.IsZero() {
@@ -129,6 +153,10 @@ func performSnipe(ctx context.Context, t *tracked, upcomingAt time.Time) (err er
 	defer func() {
 		delSnipeTask(task)
 	}()
+	if until(upcomingAt) > time.Minute {
+		// remember what they told us so we can see how reliable it was
+		t.SetAnnouncedAt(upcomingAt)
+	}
 	t.BeginSnipe(ctx)
 	runHooks("begin-snipe", map[string]interface{}{
 		"Name": task.name,
@@ -138,7 +166,7 @@ func performSnipe(ctx context.Context, t *tracked, upcomingAt time.Time) (err er
 	log.Println("track.snipe:", task.name)
 
 	// wait until we expect the target to stream
-	check := time.NewTimer(time.Until(upcomingAt))
+	check := getClock().NewTimer(until(upcomingAt))
 	defer check.Stop()
 
 	for {
@@ -149,8 +177,8 @@ func performSnipe(ctx context.Context, t *tracked, upcomingAt time.Time) (err er
 		case <-t.cancel:
 			log.Println("track.snipe:", task.name, "canceled")
 			return
-		case <-check.C:
-			err = waitForLive(ctx, t, snipeTimeout)
+		case <-check.C():
+			err = waitForLive(ctx, t, snipeTimeout, backoff.Stream)
 			if err != nil {
 				return
 			}
@@ -158,7 +186,13 @@ func performSnipe(ctx context.Context, t *tracked, upcomingAt time.Time) (err er
 			log.Println("track.snipe:", task.name, "is online")
 
 			var streamURL string
-			streamURL, err = waitForStream(ctx, t, snipeTimeout)
+			streamURL, err = waitForStream(ctx, t, snipeTimeout, backoff.Stream)
+			if errors.Is(err, ErrTicketRequired) {
+				// they are live but we cannot watch
+				log.Println("track.snipe:", task.name, "is live but a ticket is required")
+				t.setTicketRequired()
+				return
+			}
 			if err != nil {
 				// we failed to find a stream url
 				log.Println("track.snipe:", task.name, "did not find url")
@@ -173,72 +207,62 @@ func performSnipe(ctx context.Context, t *tracked, upcomingAt time.Time) (err er
 	}
 }
 
-func waitForLive(ctx context.Context, t *tracked, timeout time.Duration) (err error) {
-	to := time.NewTimer(timeout)
+// a module that says a target is not live without an error may be asked again
+var errNotLive = retry.Temporary(fmt.Errorf("track.waitForLive: %w", ErrNotLive))
+
+// op names the backoff policy to use. see backoff.For.
+func waitForLive(ctx context.Context, t *tracked, timeout time.Duration, op string) (err error) {
+	to := getClock().NewTimer(timeout)
 	defer to.Stop()
 
 	name := t.Name()
 
 	// check if the user is online
-	var ok bool
-	var isLive bool
-	var liveErr retry.BoolRetryable
-	numAttempts := 0
-	isLive, err = t.CheckLive(ctx)
-	if isLive {
-		return
-	}
-	liveErr, ok = retry.BoolCheck(err)
-	for ; ok; liveErr, ok = retry.BoolCheck(err) {
-		select {
-		case <-time.After(backoff.DefaultPolicy.Duration(numAttempts)):
-			numAttempts++
-			isLive, err = liveErr.Retry()
-			if isLive {
-				return
-			}
-		case <-to.C:
-			log.Println("track.waitForLive:", name, "timeout")
-			err = errSnipeTimeout
-			return
-		case <-ctx.Done():
-			log.Println("track.waitForLive:", name, ctx.Err())
-			return
+	_, err = retry.Do(ctx, retry.Options{
+		Policy:  backoff.For(t.Hostname(), op),
+		After:   getClock().After,
+		Timeout: to.C(),
+	}, func(ctx context.Context) (bool, error) {
+		isLive, err := t.CheckLive(ctx)
+		if err == nil && !isLive {
+			err = errNotLive
 		}
+		return isLive, err
+	})
+	switch {
+	case err == retry.ErrTimeout:
+		log.Println("track.waitForLive:", name, "timeout")
+		err = errSnipeTimeout
+	case ctx.Err() != nil:
+		log.Println("track.waitForLive:", name, ctx.Err())
 	}
 
 	return
 }
 
-func waitForStream(ctx context.Context, t *tracked, timeout time.Duration) (streamURL string, err error) {
-	to := time.NewTimer(timeout)
+func waitForStream(ctx context.Context, t *tracked, timeout time.Duration, op string) (streamURL string, err error) {
+	to := getClock().NewTimer(timeout)
 	defer to.Stop()
 
 	name := t.Name()
 
 	// check to see if the target's stream has actually begun
-	var ok bool
-	var urlErr retry.StringRetryable
-	numAttempts := 0
-	streamURL, err = t.CheckStream(ctx)
-	urlErr, ok = retry.StringCheck(err)
-	for ; ok; urlErr, ok = retry.StringCheck(err) {
-		select {
-		case <-time.After(backoff.DefaultPolicy.Duration(numAttempts)):
-			numAttempts++
-			streamURL, err = urlErr.Retry()
-			if err == nil {
-				break
+	streamURL, err = retry.Do(ctx, retry.Options{
+		Policy:  backoff.For(t.Hostname(), op),
+		After:   getClock().After,
+		Timeout: to.C(),
+		OnAttempt: func(n int, err error) {
+			if n > 1 {
+				log.Println("track.waitForStream:", err)
 			}
-			log.Println("track.waitForStream:", err)
-		case <-ctx.Done():
-			log.Println("track.waitForStream:", name, ctx.Err())
-			return
-		case <-to.C:
-			log.Println("track.waitForStream:", name, "timeout while looking for stream url")
-			err = errSnipeTimeout
-			return
-		}
+		},
+	}, t.CheckStream)
+	switch {
+	case err == retry.ErrTimeout:
+		log.Println("track.waitForStream:", name, "timeout while looking for stream url")
+		err = errSnipeTimeout
+	case ctx.Err() != nil:
+		log.Println("track.waitForStream:", name, ctx.Err())
 	}
 
 	return
@@ -17,6 +17,7 @@ package track
 
 import (
 	"context"
+	"errors"
 )
 
 // Target is being tracked for stream activity
@@ -32,16 +33,39 @@ type Target interface {
 	SavePath() string
 
 	// check for live status
+	// an error wrapping ErrNotLive means we asked and they are offline
 	CheckLive(context.Context) (bool, error)
 	// check for a live stream
+	// gives ErrTicketRequired if the target is live but we are not allowed to watch
 	CheckStream(context.Context) (string, error)
 
 	// callback when sniping starts
 	BeginSnipe(context.Context)
 	// callback when save starts
+	// use SavePathFromContext to find where we are saving
 	BeginSave(context.Context)
 	// callback when save ends
 	EndSave(context.Context)
 	// callback when user requests reload
 	Reload(context.Context)
 }
+
+// ErrTicketRequired is given when a target is live but watching needs a ticket or membership
+var ErrTicketRequired = errors.New("a ticket is required to watch this stream")
+
+// ErrNotLive is given by CheckLive when a target is offline
+var ErrNotLive = errors.New("not live")
+
+// LiveIDer is implemented by targets that can tell one live from another
+type LiveIDer interface {
+	// gives an id for the current live
+	// empty if it is not known
+	LiveID() string
+}
+
+// Cookier is implemented by targets whose streams need cookies
+type Cookier interface {
+	// gives a Cookie header for the stream url
+	// empty if there are no cookies for it
+	Cookies(streamURL string) string
+}
@@ -36,9 +36,14 @@ import (
 var rw sync.RWMutex
 var tracking = make(map[string]*tracked)
 
+// each tracked target is given a unique id that never changes
+var lastID int
+
 func beginTracking(t *tracked) {
 	rw.Lock()
 	defer rw.Unlock()
+	lastID++
+	t.id = lastID
 	tracking[t.Link()] = t
 }
 
@@ -51,6 +56,23 @@ func getTracking(link string) *tracked {
 	return nil
 }
 
+// finds a target tracked with this link or another link to the same target
+func findTracking(link string) *tracked {
+	key := linkKey(link)
+
+	rw.RLock()
+	defer rw.RUnlock()
+	if t, ok := tracking[link]; ok {
+		return t
+	}
+	for l, t := range tracking {
+		if linkKey(l) == key {
+			return t
+		}
+	}
+	return nil
+}
+
 func endTracking(link string) (removed *tracked) {
 	rw.Lock()
 	defer rw.Unlock()
@@ -95,7 +117,7 @@ func Start(ctx context.Context) error {
 		}
 		defer w.Close()
 
-		if err := w.Add(listPath); err != nil {
+		if err := w.Add(listPath()); err != nil {
 			log.Println("track.Start: cannot watch track list:", err)
 			return
 		}
@@ -139,7 +161,8 @@ func Wait() {
 
 // AddTarget for tracking
 func AddTarget(ctx context.Context, link string) error {
-	if getTracking(link) != nil {
+	link = normalizeLink(ctx, link)
+	if findTracking(link) != nil {
 		// silently ignore attempt to add a target we already have
 		return nil
 	}
@@ -150,7 +173,7 @@ func AddTarget(ctx context.Context, link string) error {
 	}
 
 	host := u.Hostname()
-	m, err := FindModule(host)
+	m, err := FindModuleForLink(link)
 	if err != nil {
 		return err
 	}
@@ -160,6 +183,9 @@ func AddTarget(ctx context.Context, link string) error {
 	// if there was an error but the link was valid the module should return a target
 	// if the module is unable to handle this link it should return nil
 	if target == nil {
+		if err != nil {
+			return fmt.Errorf("track.AddTarget: %s: %w", link, err)
+		}
 		return fmt.Errorf("track.AddTarget: link was not accepted: %q", link)
 	}
 
@@ -167,7 +193,7 @@ func AddTarget(ctx context.Context, link string) error {
 	added := &tracked{
 		target:   target,
 		cancel:   make(chan struct{}),
-		hostname: host,
+		hostname: m.Hostname(),
 	}
 	beginTracking(added)
 
@@ -179,7 +205,7 @@ func AddTarget(ctx context.Context, link string) error {
 	if _, err := target.CheckStream(ctx); err == nil {
 		log.Println("track.AddTarget:", target.Name(), "is live now!")
 		// they are live now so try to snipe them now
-		if err = snipeAt(ctx, added, time.Now()); err != nil {
+		if err = snipeAt(ctx, added, Now()); err != nil {
 			log.Println("track.AddTarget:", err)
 		}
 	}
@@ -218,6 +244,46 @@ func CancelTarget(link string) error {
 	return nil
 }
 
+// StopSave ends the current save for a target but keeps tracking them
+func StopSave(link string) error {
+	t := getTracking(link)
+	if t == nil {
+		return fmt.Errorf("track.StopSave: did not find: %s", link)
+	}
+	if !t.StopSave() {
+		return fmt.Errorf("track.StopSave: not saving: %s", link)
+	}
+
+	return nil
+}
+
+// UpdateMetadata writes the save metadata for a target again
+// targets call this when details they describe have changed
+func UpdateMetadata(link string) error {
+	t := getTracking(link)
+	if t == nil {
+		return fmt.Errorf("track.UpdateMetadata: did not find: %s", link)
+	}
+	if !t.UpdateMetadata() {
+		return fmt.Errorf("track.UpdateMetadata: not saving: %s", link)
+	}
+
+	return nil
+}
+
+// SavingPaths gives the paths of every file being saved right now
+func SavingPaths() (paths []string) {
+	rw.RLock()
+	defer rw.RUnlock()
+	for _, t := range tracking {
+		if p := t.SavingAs(); p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	return
+}
+
 // data is a json string
 func runHooks(name string, data map[string]interface{}) {
 	log.Println("track.runHooks:", name, data)
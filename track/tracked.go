@@ -18,6 +18,7 @@ package track
 import (
 	"context"
 	"errors"
+	"log"
 	"sync"
 	"time"
 )
@@ -26,10 +27,35 @@ import (
 // handles cancellation of target processing
 type tracked struct {
 	sync.RWMutex
+	id         int
 	target     Target
 	cancel     chan struct{}
 	finishedAt time.Time
 	hostname   string
+	// set while a save is running
+	stop chan struct{}
+	// the user stopped a save so we leave this live alone
+	stopped bool
+	// the live that was stopped if the target can tell
+	stoppedLiveID string
+	// they are live but watching needs a ticket
+	ticketRequired bool
+	// the live that needs a ticket if the target can tell
+	ticketLiveID string
+	savingAs     string
+	// signaled when the target has new details for the save metadata
+	changed chan struct{}
+	// when the target said they would be live
+	announcedAt time.Time
+	// when poll should check the target next
+	nextCheckAt time.Time
+}
+
+// ID stays the same for as long as we track the target
+func (t *tracked) ID() int {
+	t.RLock()
+	defer t.RUnlock()
+	return t.id
 }
 
 func (t *tracked) Display() string {
@@ -62,6 +88,28 @@ func (t *tracked) Link() string {
 	return t.target.Link()
 }
 
+// Title of the stream if the target gives one
+func (t *tracked) Title() string {
+	t.RLock()
+	defer t.RUnlock()
+	if d, ok := t.target.(Titler); ok {
+		return d.Title()
+	}
+
+	return ""
+}
+
+// Cookies for downloading a stream if the target gives them
+func (t *tracked) Cookies(streamURL string) string {
+	t.RLock()
+	defer t.RUnlock()
+	if c, ok := t.target.(Cookier); ok {
+		return c.Cookies(streamURL)
+	}
+
+	return ""
+}
+
 func (t *tracked) Hostname() string {
 	t.RLock()
 	defer t.RUnlock()
@@ -118,9 +166,163 @@ func (t *tracked) Cancel() {
 	close(t.cancel)
 }
 
+// beginSave gives a channel that is closed when the user asks us to stop saving
+func (t *tracked) beginSave() <-chan struct{} {
+	t.Lock()
+	defer t.Unlock()
+	t.stop = make(chan struct{})
+	t.changed = make(chan struct{}, 1)
+	return t.stop
+}
+
+// metadataChanged gives a channel that is signaled when the save metadata should be written again
+func (t *tracked) metadataChanged() <-chan struct{} {
+	t.RLock()
+	defer t.RUnlock()
+	return t.changed
+}
+
+// UpdateMetadata asks for the save metadata to be written again
+// gives false if we were not saving
+func (t *tracked) UpdateMetadata() bool {
+	t.RLock()
+	defer t.RUnlock()
+	if t.changed == nil {
+		return false
+	}
+	select {
+	case t.changed <- struct{}{}:
+	default:
+		// an update is already waiting
+	}
+	return true
+}
+
+func (t *tracked) endSave() {
+	t.Lock()
+	defer t.Unlock()
+	t.stop = nil
+	t.changed = nil
+	t.savingAs = ""
+}
+
+// StopSave ends the current save without removing the target
+// gives false if we were not saving
+func (t *tracked) StopSave() bool {
+	t.Lock()
+	defer t.Unlock()
+	if t.stop == nil {
+		return false
+	}
+	close(t.stop)
+	t.stop = nil
+	t.stopped = true
+	t.stoppedLiveID = t.liveID()
+	return true
+}
+
+// gives the id of the current live if the target can tell
+// caller holds the lock
+func (t *tracked) liveID() string {
+	if l, ok := t.target.(LiveIDer); ok {
+		return l.LiveID()
+	}
+	return ""
+}
+
+// isStopped is true while the user does not want the current live saved
+// a new live lets us save again
+func (t *tracked) isStopped() bool {
+	t.Lock()
+	defer t.Unlock()
+	if !t.stopped {
+		return false
+	}
+	if id := t.liveID(); id != "" && id != t.stoppedLiveID {
+		log.Println("track.isStopped:", t.target.Name(), "has a new live")
+		t.stopped = false
+	}
+	return t.stopped
+}
+
+// setTicketRequired remembers their current live needs a ticket
+func (t *tracked) setTicketRequired() {
+	t.Lock()
+	defer t.Unlock()
+	t.ticketRequired = true
+	t.ticketLiveID = t.liveID()
+}
+
+// isTicketRequired is true while their current live needs a ticket
+// a new live may not so we forget it
+func (t *tracked) isTicketRequired() bool {
+	t.Lock()
+	defer t.Unlock()
+	if !t.ticketRequired {
+		return false
+	}
+	if id := t.liveID(); id != "" && id != t.ticketLiveID {
+		log.Println("track.isTicketRequired:", t.target.Name(), "has a new live")
+		t.ticketRequired = false
+	}
+	return t.ticketRequired
+}
+
+// seenOffline lets us save their next live
+func (t *tracked) seenOffline() {
+	t.Lock()
+	defer t.Unlock()
+	if t.stopped || t.ticketRequired {
+		log.Println("track.seenOffline:", t.target.Name(), "is offline")
+	}
+	t.stopped = false
+	t.ticketRequired = false
+}
+
+// SavingAs is the path of the file being saved
+func (t *tracked) SavingAs() string {
+	t.RLock()
+	defer t.RUnlock()
+	return t.savingAs
+}
+
+func (t *tracked) setSavingAs(saveAs string) {
+	t.Lock()
+	defer t.Unlock()
+	t.savingAs = saveAs
+}
+
+// AnnouncedAt is when the target said their next stream would begin
+func (t *tracked) AnnouncedAt() time.Time {
+	t.RLock()
+	defer t.RUnlock()
+	return t.announcedAt
+}
+
+// SetAnnouncedAt for target
+func (t *tracked) SetAnnouncedAt(at time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	t.announcedAt = at
+}
+
+// NextCheckAt is when poll plans to check the target
+func (t *tracked) NextCheckAt() time.Time {
+	t.RLock()
+	defer t.RUnlock()
+	return t.nextCheckAt
+}
+
+// SetNextCheckAt for target
+func (t *tracked) SetNextCheckAt(at time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	t.nextCheckAt = at
+}
+
 // IsUpcoming is true if the target has a known upcoming time
 func (t *tracked) IsUpcoming() bool {
-	return time.Until(t.UpcomingAt().Add(snipeTimeout)) > 0
+	return until(t.UpcomingAt().Add(snipeTimeout)) > 0
 }
 
 // IsLive is true if the target is live
@@ -0,0 +1,168 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package tracktest helps test tracking without real time, programs or websites
+package tracktest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/track"
+)
+
+// Clock only moves when it is told to
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+// someone waiting for the clock to reach a time
+type waiter struct {
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewClock that begins at now
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now gives the current fake time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) add(d time.Duration, period time.Duration) *waiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &waiter{
+		at:     c.now.Add(d),
+		period: period,
+		c:      make(chan time.Time, 1),
+	}
+	if d <= 0 && period == 0 {
+		w.c <- c.now
+		return w
+	}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w
+}
+
+func (c *Clock) remove(w *waiter) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ndx, ww := range c.waiters {
+		if ww == w {
+			c.waiters = append(c.waiters[:ndx], c.waiters[ndx+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// After gives a channel that receives once the clock has moved d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.add(d, 0).c
+}
+
+// NewTimer that fires once the clock has moved d
+func (c *Clock) NewTimer(d time.Duration) track.Timer {
+	return &timer{c: c, w: c.add(d, 0)}
+}
+
+// NewTicker that fires each time the clock moves d
+func (c *Clock) NewTicker(d time.Duration) track.Ticker {
+	return &ticker{timer{c: c, w: c.add(d, d)}}
+}
+
+// Advance moves the clock forward and fires every timer that is due in order
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		sort.SliceStable(c.waiters, func(a, b int) bool {
+			return c.waiters[a].at.Before(c.waiters[b].at)
+		})
+		if len(c.waiters) == 0 || c.waiters[0].at.After(end) {
+			break
+		}
+
+		w := c.waiters[0]
+		c.now = w.at
+		select {
+		case w.c <- c.now:
+		default:
+			// a ticker drops ticks nobody is waiting for
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = end
+	c.cond.Broadcast()
+}
+
+// BlockUntil waits until n timers are waiting on the clock
+// this lets goroutines reach their timers before the clock moves
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// Waiting gives how many timers are waiting on the clock
+func (c *Clock) Waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+type timer struct {
+	c *Clock
+	w *waiter
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.w.c
+}
+
+func (t *timer) Stop() bool {
+	return t.c.remove(t.w)
+}
+
+type ticker struct {
+	timer
+}
+
+func (t *ticker) Stop() {
+	t.timer.Stop()
+}
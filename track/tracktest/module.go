@@ -0,0 +1,293 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package tracktest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bobbytrapz/autosr/retry"
+	"github.com/bobbytrapz/autosr/track"
+)
+
+// Module is a pretend site
+// targets are added with Add before they are tracked
+type Module struct {
+	hostname string
+
+	mu      sync.Mutex
+	targets map[string]*Target
+	// each CheckUpcoming is sent here with the links it was given
+	checked chan []string
+}
+
+// NewModule for a hostname
+func NewModule(hostname string) *Module {
+	return &Module{
+		hostname: hostname,
+		targets:  make(map[string]*Target),
+		checked:  make(chan []string, 64),
+	}
+}
+
+// Hostname of the pretend site
+func (m *Module) Hostname() string {
+	return m.hostname
+}
+
+// Add a target the module knows about
+func (m *Module) Add(t *Target) *Target {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.targets[t.Link()] = t
+	return t
+}
+
+// AddTarget gives a target added with Add
+func (m *Module) AddTarget(ctx context.Context, link string) (track.Target, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.targets[link]
+	if !ok {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// Checked gives the links given to each CheckUpcoming
+func (m *Module) Checked() <-chan []string {
+	return m.checked
+}
+
+// CheckUpcoming snipes live targets now and others at their upcoming time
+func (m *Module) CheckUpcoming(ctx context.Context, targets []track.Target) error {
+	var links []string
+	for _, tt := range targets {
+		links = append(links, tt.Link())
+		t, ok := tt.(*Target)
+		if !ok {
+			continue
+		}
+
+		at := t.Upcoming()
+		if t.IsLive() {
+			at = track.Now()
+		}
+		if at.IsZero() {
+			continue
+		}
+		if err := track.SnipeTargetAt(ctx, t, at); err != nil {
+			log.Println("tracktest.CheckUpcoming:", err)
+		}
+	}
+
+	select {
+	case m.checked <- links:
+	default:
+	}
+
+	return nil
+}
+
+// Target is a pretend streamer whose behaviour is set by the test
+type Target struct {
+	name string
+	link string
+
+	mu        sync.Mutex
+	live      bool
+	streamURL string
+	upcoming  time.Time
+	// live but watching needs a ticket
+	ticketed bool
+	// answers given before the current state
+	liveScript   []bool
+	streamScript []string
+	// each callback is sent here by name
+	events chan string
+}
+
+// NewTarget that is offline
+func NewTarget(name, link string) *Target {
+	return &Target{
+		name:   name,
+		link:   link,
+		events: make(chan string, 64),
+	}
+}
+
+// SetLive makes the target live with a stream
+// an empty stream url makes the target live without a stream yet
+func (t *Target) SetLive(streamURL string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.live = true
+	t.streamURL = streamURL
+}
+
+// SetOffline ends the target's stream
+func (t *Target) SetOffline() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.live = false
+	t.streamURL = ""
+}
+
+// LiveID is the stream url so a new stream is a new live
+func (t *Target) LiveID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.live {
+		return ""
+	}
+	return t.streamURL
+}
+
+// SetUpcoming announces the next stream
+func (t *Target) SetUpcoming(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.upcoming = at
+}
+
+// SetTicketed makes a live need a ticket to watch
+func (t *Target) SetTicketed(ticketed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ticketed = ticketed
+}
+
+// ScriptLive gives these answers to CheckLive before the current state
+func (t *Target) ScriptLive(answers ...bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.liveScript = append(t.liveScript, answers...)
+}
+
+// ScriptStream gives these stream urls to CheckStream before the current state
+// an empty url means there is no stream yet
+func (t *Target) ScriptStream(urls ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.streamScript = append(t.streamScript, urls...)
+}
+
+// IsLive is the current state
+func (t *Target) IsLive() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.live
+}
+
+// Upcoming is the announced time if there is one
+func (t *Target) Upcoming() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.upcoming
+}
+
+// Events gives the name of each callback as it is called
+func (t *Target) Events() <-chan string {
+	return t.events
+}
+
+func (t *Target) event(name string) {
+	select {
+	case t.events <- name:
+	default:
+	}
+}
+
+// Name of the target
+func (t *Target) Name() string {
+	return t.name
+}
+
+// Display of the target
+func (t *Target) Display() string {
+	return t.name
+}
+
+// Link of the target
+func (t *Target) Link() string {
+	return t.link
+}
+
+// SavePath of the target
+func (t *Target) SavePath() string {
+	return t.name
+}
+
+// CheckLive gives the next scripted answer or the current state
+// not being live can be retried like a real module
+func (t *Target) CheckLive(ctx context.Context) (bool, error) {
+	t.mu.Lock()
+	isLive := t.live
+	if len(t.liveScript) > 0 {
+		isLive, t.liveScript = t.liveScript[0], t.liveScript[1:]
+	}
+	t.mu.Unlock()
+
+	if isLive {
+		return true, nil
+	}
+
+	return false, retry.Temporary(fmt.Errorf("%s is %w", t.name, track.ErrNotLive))
+}
+
+// CheckStream gives the next scripted url or the current stream
+// no stream can be retried like a real module
+func (t *Target) CheckStream(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	if t.live && t.ticketed {
+		t.mu.Unlock()
+		return "", fmt.Errorf("%s: %w", t.name, track.ErrTicketRequired)
+	}
+	streamURL := t.streamURL
+	if len(t.streamScript) > 0 {
+		streamURL, t.streamScript = t.streamScript[0], t.streamScript[1:]
+	}
+	t.mu.Unlock()
+
+	if streamURL != "" {
+		return streamURL, nil
+	}
+
+	return "", retry.Temporary(fmt.Errorf("%s has no stream yet", t.name))
+}
+
+// BeginSnipe callback
+func (t *Target) BeginSnipe(ctx context.Context) {
+	t.event("BeginSnipe")
+}
+
+// BeginSave callback
+func (t *Target) BeginSave(ctx context.Context) {
+	t.event("BeginSave")
+}
+
+// EndSave callback
+func (t *Target) EndSave(ctx context.Context) {
+	t.event("EndSave")
+}
+
+// Reload callback
+func (t *Target) Reload(ctx context.Context) {
+	t.event("Reload")
+}
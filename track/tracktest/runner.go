@@ -0,0 +1,120 @@
+// This file is part of autosr.
+//
+// autosr is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// autosr is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with autosr.  If not, see <https://www.gnu.org/licenses/>.
+
+package tracktest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/bobbytrapz/autosr/track"
+)
+
+// ErrKilled is given by Wait for a process that was killed
+var ErrKilled = errors.New("tracktest: killed")
+
+// Runner pretends to run downloaders
+type Runner struct {
+	mu      sync.Mutex
+	lastPid int
+	// each process is sent here when it starts
+	started chan *Process
+}
+
+// NewRunner that pretends to run downloaders
+func NewRunner() *Runner {
+	return &Runner{
+		started: make(chan *Process, 16),
+	}
+}
+
+// Started gives each downloader as it starts
+func (r *Runner) Started() <-chan *Process {
+	return r.started
+}
+
+// Start a pretend downloader
+func (r *Runner) Start(ctx context.Context, app string, args ...string) (track.Process, error) {
+	r.mu.Lock()
+	r.lastPid++
+	p := &Process{
+		App:  app,
+		Args: args,
+		pid:  r.lastPid,
+		exit: make(chan error, 1),
+	}
+	r.mu.Unlock()
+
+	r.started <- p
+	return p, nil
+}
+
+// Process is a pretend downloader
+// it runs until it is told to exit or it is killed
+type Process struct {
+	App  string
+	Args []string
+
+	pid  int
+	once sync.Once
+	exit chan error
+
+	mu     sync.Mutex
+	killed bool
+}
+
+// Pid of the pretend process
+func (p *Process) Pid() int {
+	return p.pid
+}
+
+// Wait for the process to exit
+func (p *Process) Wait() error {
+	return <-p.exit
+}
+
+// Kill the process
+func (p *Process) Kill() error {
+	p.mu.Lock()
+	p.killed = true
+	p.mu.Unlock()
+	p.Exit(ErrKilled)
+	return nil
+}
+
+// Killed is true if the process was killed
+func (p *Process) Killed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.killed
+}
+
+// Exit makes the process exit as if the stream ended
+func (p *Process) Exit(err error) {
+	p.once.Do(func() {
+		p.exit <- err
+	})
+}
+
+// HasArg is true if the downloader was given arg
+func (p *Process) HasArg(arg string) bool {
+	for _, a := range p.Args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}